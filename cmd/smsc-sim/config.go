@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+// Config holds simulator configuration loaded from JSON file.
+type Config struct {
+	// Addr is the address simulator listens on for SMPP connections.
+	Addr string `json:"addr"`
+	// HTTPAddr is the address of HTTP API used for injecting MO messages.
+	// HTTP API is disabled if empty.
+	HTTPAddr string `json:"http_addr"`
+	// SystemID is sent to the peers in bind responses.
+	SystemID string `json:"system_id"`
+	// Accounts allowed to bind. Any system_id and password is accepted if empty.
+	Accounts []Account `json:"accounts"`
+	// IDFormat defines how message IDs are formatted, "dec" or "hex".
+	IDFormat string `json:"id_format"`
+	// MaxMessages is the number of submitted messages kept for query_sm,
+	// the oldest are forgotten first. Defaults to 100000.
+	MaxMessages int      `json:"max_messages"`
+	Receipts    Receipts `json:"receipts"`
+}
+
+// Account holds credentials of the single ESME.
+type Account struct {
+	SystemID string `json:"system_id"`
+	Password string `json:"password"`
+}
+
+// Receipts defines how and when delivery receipts are generated.
+type Receipts struct {
+	// Delay between submit_sm and the final state of the message.
+	Delay Duration `json:"delay"`
+	// Outcomes maps final message states (DELIVRD, UNDELIV, EXPIRED...) to
+	// their relative weights.
+	Outcomes map[pdu.DeliveryStat]int `json:"outcomes"`
+	// ErrorCodes maps final message states to the err value reported in
+	// the receipt.
+	ErrorCodes map[pdu.DeliveryStat]int `json:"error_codes"`
+}
+
+// Duration wraps time.Duration to allow "2s" notation in JSON.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("smsc-sim: duration must be a string: %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// LoadConfig reads configuration from file and fills in defaults.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	conf := &Config{}
+	if err := json.NewDecoder(f).Decode(conf); err != nil {
+		return nil, fmt.Errorf("smsc-sim: decoding %s: %v", path, err)
+	}
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func (conf *Config) validate() error {
+	if conf.Addr == "" {
+		conf.Addr = ":2775"
+	}
+	if conf.SystemID == "" {
+		conf.SystemID = "smsc-sim"
+	}
+	if conf.MaxMessages < 0 {
+		return fmt.Errorf("smsc-sim: negative max_messages %d", conf.MaxMessages)
+	}
+	if conf.MaxMessages == 0 {
+		conf.MaxMessages = 100000
+	}
+	switch conf.IDFormat {
+	case "":
+		conf.IDFormat = "dec"
+	case "dec", "hex":
+	default:
+		return fmt.Errorf("smsc-sim: invalid id_format %q", conf.IDFormat)
+	}
+	if len(conf.Receipts.Outcomes) == 0 {
+		conf.Receipts.Outcomes = map[pdu.DeliveryStat]int{pdu.DelStatDelivered: 1}
+	}
+	total := 0
+	for stat, w := range conf.Receipts.Outcomes {
+		if stat.MessageState() == 0 {
+			return fmt.Errorf("smsc-sim: unknown receipt outcome %q", stat)
+		}
+		if w < 0 {
+			return fmt.Errorf("smsc-sim: negative weight for outcome %q", stat)
+		}
+		total += w
+	}
+	if total == 0 {
+		return errors.New("smsc-sim: receipt outcome weights sum to zero")
+	}
+	return nil
+}
+
+// authenticate checks system_id and password against configured accounts.
+func (conf *Config) authenticate(systemID, password string) pdu.Status {
+	if len(conf.Accounts) == 0 {
+		return pdu.StatusOK
+	}
+	for _, acc := range conf.Accounts {
+		if acc.SystemID != systemID {
+			continue
+		}
+		if acc.Password != password {
+			return pdu.StatusInvPaswd
+		}
+		return pdu.StatusOK
+	}
+	return pdu.StatusInvSysID
+}
+
+// outcome picks final message state using configured weights.
+func (r Receipts) outcome(rnd *rand.Rand) pdu.DeliveryStat {
+	total := 0
+	for _, w := range r.Outcomes {
+		total += w
+	}
+	n := rnd.Intn(total)
+	// Iterate in fixed order so the same random source gives the same results.
+	for _, stat := range finalStates {
+		w := r.Outcomes[stat]
+		if n < w {
+			return stat
+		}
+		n -= w
+	}
+	return pdu.DelStatDelivered
+}
+
+var finalStates = []pdu.DeliveryStat{
+	pdu.DelStatDelivered,
+	pdu.DelStatUndeliverable,
+	pdu.DelStatExpired,
+	pdu.DelStatDeleted,
+	pdu.DelStatRejected,
+	pdu.DelStatUnknown,
+	pdu.DelStatAccepted,
+	pdu.DelStatEnRoute,
+}
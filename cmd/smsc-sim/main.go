@@ -0,0 +1,83 @@
+// Command smsc-sim is a reference SMSC implementation meant for end-to-end
+// testing of SMPP clients without access to an operator.
+//
+// It accepts binds of configured accounts, assigns message IDs to submitted
+// messages, answers query_sm and sends delivery receipts with configurable
+// delay and outcome distribution. Mobile originated messages can be injected
+// through HTTP API.
+//
+// Usage:
+//
+//	go run ./cmd/smsc-sim -config smsc-sim.json
+//	curl -X POST 'localhost:8080/mo?system_id=esme&source=111&destination=222&text=hello'
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	var (
+		confPath string
+		addr     string
+		httpAddr string
+	)
+	flag.StringVar(&confPath, "config", "", "path to JSON configuration file.")
+	flag.StringVar(&addr, "addr", "", "SMPP listen address, overrides configuration.")
+	flag.StringVar(&httpAddr, "http", "", "MO injection HTTP address, overrides configuration.")
+	flag.Parse()
+
+	conf := &Config{}
+	if confPath != "" {
+		var err error
+		conf, err = LoadConfig(confPath)
+		if err != nil {
+			fail("Can't load configuration: %v", err)
+		}
+	} else if err := conf.validate(); err != nil {
+		fail("Invalid configuration: %v", err)
+	}
+	if addr != "" {
+		conf.Addr = addr
+	}
+	if httpAddr != "" {
+		conf.HTTPAddr = httpAddr
+	}
+
+	sim := NewSimulator(conf)
+	if conf.HTTPAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(conf.HTTPAddr, sim); err != nil {
+				fail("HTTP API exited with error: %v", err)
+			}
+		}()
+	}
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := sim.Shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Shutdown: %v\n", err)
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "'%s' is listening on '%s'\n", conf.SystemID, conf.Addr)
+	if err := sim.ListenAndServe(); err != nil {
+		fail("Serving exited with error: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Server closed\n")
+}
+
+func fail(msg string, params ...interface{}) {
+	fmt.Fprintf(os.Stderr, msg+"\n", params...)
+	os.Exit(1)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/majiddarvishan/smpp"
+	"github.com/majiddarvishan/smpp/pdu"
+	"github.com/majiddarvishan/smpp/utility"
+)
+
+// message is a short message accepted by the simulator.
+type message struct {
+	id        string
+	systemID  string
+	sm        *pdu.SubmitSm
+	submitted time.Time
+	done      time.Time
+	stat      pdu.DeliveryStat
+	errCode   int
+}
+
+// Simulator is SMSC simulator built on top of smpp.Server.
+type Simulator struct {
+	conf *Config
+	srv  *smpp.Server
+
+	mu       sync.Mutex
+	rnd      *rand.Rand
+	lastID   uint64
+	messages map[string]*message
+	// order holds IDs of stored messages, oldest first.
+	order []string
+	// receivers holds sessions capable of receiving deliver_sm by system_id.
+	receivers map[string]map[string]*smpp.Session
+	// timers holds pending final state transitions by message ID.
+	timers map[string]*time.Timer
+}
+
+// NewSimulator creates simulator for provided configuration.
+func NewSimulator(conf *Config) *Simulator {
+	sim := &Simulator{
+		conf:      conf,
+		rnd:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		messages:  make(map[string]*message),
+		receivers: make(map[string]map[string]*smpp.Session),
+		timers:    make(map[string]*time.Timer),
+	}
+	sim.srv = smpp.NewServer(conf.Addr, smpp.SessionConf{
+		RequestHandler:  smpp.RequestHandlerFunc(sim.serveRequest),
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {}),
+		SessionState:    sim.sessionState,
+	})
+	return sim
+}
+
+// ListenAndServe starts accepting SMPP connections. Blocking function.
+func (sim *Simulator) ListenAndServe() error {
+	return sim.srv.ListenAndServe()
+}
+
+// Shutdown stops pending receipts and gracefully unbinds all sessions.
+func (sim *Simulator) Shutdown(ctx context.Context) error {
+	sim.mu.Lock()
+	for _, t := range sim.timers {
+		t.Stop()
+	}
+	sim.timers = make(map[string]*time.Timer)
+	sim.mu.Unlock()
+	return sim.srv.Shutdown(ctx)
+}
+
+func (sim *Simulator) serveRequest(ctx *smpp.Context) {
+	switch ctx.CommandID() {
+	case pdu.BindTransceiverID:
+		p, err := ctx.BindTRx()
+		if err != nil {
+			return
+		}
+		sim.bind(ctx, p.SystemID, p.Password, p.Response(sim.conf.SystemID), true)
+	case pdu.BindReceiverID:
+		p, err := ctx.BindRx()
+		if err != nil {
+			return
+		}
+		sim.bind(ctx, p.SystemID, p.Password, p.Response(sim.conf.SystemID), true)
+	case pdu.BindTransmitterID:
+		p, err := ctx.BindTx()
+		if err != nil {
+			return
+		}
+		sim.bind(ctx, p.SystemID, p.Password, p.Response(sim.conf.SystemID), false)
+	case pdu.SubmitSmID:
+		sm, err := ctx.SubmitSm()
+		if err != nil {
+			return
+		}
+		msg := sim.submit(ctx.Sess.SystemID(), sm)
+		if err := ctx.Respond(sm.Response(msg.id), pdu.StatusOK); err != nil {
+			log.Printf("smsc-sim: responding to submit_sm: %v", err)
+		}
+	case pdu.QuerySmID:
+		qsm, err := ctx.QuerySm()
+		if err != nil {
+			return
+		}
+		sim.mu.Lock()
+		msg, ok := sim.messages[qsm.MessageID]
+		var resp *pdu.QuerySmResp
+		if ok {
+			resp = qsm.Response(msg.done, msg.stat.MessageState(), msg.errCode)
+		}
+		sim.mu.Unlock()
+		if !ok {
+			ctx.Respond(&pdu.QuerySmResp{MessageID: qsm.MessageID}, pdu.StatusInvMsgID)
+			return
+		}
+		ctx.Respond(resp, pdu.StatusOK)
+	case pdu.EnquireLinkID:
+		el, err := ctx.EnquireLink()
+		if err != nil {
+			return
+		}
+		ctx.Respond(el.Response(), pdu.StatusOK)
+	case pdu.UnbindID:
+		ubd, err := ctx.Unbind()
+		if err != nil {
+			return
+		}
+		ctx.Respond(ubd.Response(), pdu.StatusOK)
+		ctx.CloseSession()
+	default:
+		ctx.Respond(&pdu.GenericNack{}, pdu.StatusInvCmdID)
+	}
+}
+
+func (sim *Simulator) bind(ctx *smpp.Context, systemID, password string, resp pdu.PDU, receiver bool) {
+	status := sim.conf.authenticate(systemID, password)
+	if err := ctx.Respond(resp, status); err != nil {
+		log.Printf("smsc-sim: responding to bind: %v", err)
+		return
+	}
+	if status != pdu.StatusOK {
+		ctx.CloseSession()
+		return
+	}
+	if !receiver {
+		return
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if sim.receivers[systemID] == nil {
+		sim.receivers[systemID] = make(map[string]*smpp.Session)
+	}
+	sim.receivers[systemID][ctx.SessionID()] = ctx.Sess
+}
+
+func (sim *Simulator) sessionState(sessionID, systemID string, state smpp.SessionState, reason smpp.CloseReason) {
+	if state != smpp.StateClosing {
+		return
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	for _, sessions := range sim.receivers {
+		delete(sessions, sessionID)
+	}
+}
+
+// submit stores the message and schedules its final state.
+func (sim *Simulator) submit(systemID string, sm *pdu.SubmitSm) *message {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.lastID++
+	id := strconv.FormatUint(sim.lastID, 10)
+	if sim.conf.IDFormat == "hex" {
+		id = strconv.FormatUint(sim.lastID, 16)
+	}
+	msg := &message{
+		id:        id,
+		systemID:  systemID,
+		sm:        sm,
+		submitted: time.Now(),
+		stat:      pdu.DelStatEnRoute,
+	}
+	sim.messages[id] = msg
+	sim.order = append(sim.order, id)
+	for len(sim.order) > sim.conf.MaxMessages {
+		delete(sim.messages, sim.order[0])
+		sim.order = sim.order[1:]
+	}
+	stat := sim.conf.Receipts.outcome(sim.rnd)
+	sim.timers[id] = time.AfterFunc(sim.conf.Receipts.Delay.Duration, func() {
+		sim.finish(msg, stat)
+	})
+	return msg
+}
+
+// finish moves message to its final state and sends receipt if requested.
+func (sim *Simulator) finish(msg *message, stat pdu.DeliveryStat) {
+	sim.mu.Lock()
+	delete(sim.timers, msg.id)
+	msg.stat = stat
+	msg.done = time.Now()
+	msg.errCode = sim.conf.Receipts.ErrorCodes[stat]
+	dr := pdu.DeliveryReceipt{
+		Id:         msg.id,
+		Sub:        1,
+		SubmitDate: msg.submitted,
+		DoneDate:   msg.done,
+		Stat:       stat,
+		Err:        pdu.DeliveryErr(msg.errCode),
+	}
+	if stat == pdu.DelStatDelivered {
+		dr.Dlvrd = 1
+	}
+	sim.mu.Unlock()
+
+	dsm := pdu.NewReceiptDeliverSm(msg.sm, msg.id, dr)
+	if dsm == nil {
+		return
+	}
+	if err := sim.deliver(msg.systemID, dsm); err != nil {
+		log.Printf("smsc-sim: sending receipt for %s: %v", msg.id, err)
+	}
+}
+
+// deliver sends deliver_sm to one of the receiving sessions bound with systemID.
+func (sim *Simulator) deliver(systemID string, dsm *pdu.DeliverSm) error {
+	sim.mu.Lock()
+	var sessions []*smpp.Session
+	for _, sess := range sim.receivers[systemID] {
+		sessions = append(sessions, sess)
+	}
+	sim.mu.Unlock()
+	if len(sessions) == 0 {
+		return fmt.Errorf("no receiver bound for %q", systemID)
+	}
+	var err error
+	for _, sess := range sessions {
+		if _, err = sess.SendRequest(context.Background(), dsm); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// ServeHTTP implements MO injection API, text longer than one part is
+// delivered as concatenated deliver_sm PDUs:
+//
+//	POST /mo?system_id=esme&source=111&destination=222&text=hello
+func (sim *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/mo" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Text is sent in GSM7 when possible and in UCS2 otherwise, split
+	// into concatenated parts when it doesn't fit one short_message.
+	parts, coding, err := utility.Split(r.FormValue("text"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, part := range parts {
+		dsm := &pdu.DeliverSm{
+			SourceAddr:      r.FormValue("source"),
+			DestinationAddr: r.FormValue("destination"),
+			DataCoding:      int(coding),
+			ShortMessage:    string(part),
+		}
+		if len(parts) > 1 {
+			dsm.EsmClass.Feature = pdu.UDHIEsmFeat
+		}
+		if err := sim.deliver(r.FormValue("system_id"), dsm); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
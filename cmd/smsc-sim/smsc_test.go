@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/majiddarvishan/smpp"
+	"github.com/majiddarvishan/smpp/pdu"
+	"github.com/majiddarvishan/smpp/utility"
+)
+
+func TestReceiptOutcomeDistribution(t *testing.T) {
+	r := Receipts{Outcomes: map[pdu.DeliveryStat]int{
+		pdu.DelStatDelivered:     3,
+		pdu.DelStatUndeliverable: 1,
+	}}
+	rnd := rand.New(rand.NewSource(1))
+	counts := map[pdu.DeliveryStat]int{}
+	for i := 0; i < 4000; i++ {
+		counts[r.outcome(rnd)]++
+	}
+	if len(counts) != 2 {
+		t.Fatalf("unexpected outcomes %v", counts)
+	}
+	if d := counts[pdu.DelStatDelivered]; d < 2800 || d > 3200 {
+		t.Errorf("delivered outcome count %d is out of expected range", d)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	conf := &Config{}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Addr != ":2775" || conf.IDFormat != "dec" || conf.MaxMessages != 100000 {
+		t.Errorf("defaults not applied %+v", conf)
+	}
+	conf = &Config{Receipts: Receipts{Outcomes: map[pdu.DeliveryStat]int{"LOST": 1}}}
+	if err := conf.validate(); err == nil {
+		t.Error("expected error for unknown outcome")
+	}
+	conf = &Config{Accounts: []Account{{SystemID: "esme", Password: "secret"}}}
+	if st := conf.authenticate("esme", "wrong"); st != pdu.StatusInvPaswd {
+		t.Errorf("expected %s got %s", pdu.StatusInvPaswd, st)
+	}
+	if st := conf.authenticate("other", "secret"); st != pdu.StatusInvSysID {
+		t.Errorf("expected %s got %s", pdu.StatusInvSysID, st)
+	}
+}
+
+func TestSimulatorForgetsOldestMessages(t *testing.T) {
+	conf := &Config{MaxMessages: 2}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+	sim := NewSimulator(conf)
+	defer sim.Shutdown(context.Background())
+	var ids []string
+	for i := 0; i < 3; i++ {
+		ids = append(ids, sim.submit("esme", &pdu.SubmitSm{}).id)
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if _, ok := sim.messages[ids[0]]; ok {
+		t.Errorf("expected message %s to be forgotten", ids[0])
+	}
+	for _, id := range ids[1:] {
+		if _, ok := sim.messages[id]; !ok {
+			t.Errorf("expected message %s to be kept", id)
+		}
+	}
+}
+
+func TestSimulatorEndToEnd(t *testing.T) {
+	conf := &Config{
+		Addr:     "127.0.0.1:30320",
+		Accounts: []Account{{SystemID: "esme", Password: "secret"}},
+		Receipts: Receipts{
+			Delay:      Duration{10 * time.Millisecond},
+			Outcomes:   map[pdu.DeliveryStat]int{pdu.DelStatUndeliverable: 1},
+			ErrorCodes: map[pdu.DeliveryStat]int{pdu.DelStatUndeliverable: 11},
+		},
+	}
+	if err := conf.validate(); err != nil {
+		t.Fatal(err)
+	}
+	sim := NewSimulator(conf)
+	go sim.ListenAndServe()
+	defer sim.Shutdown(context.Background())
+	time.Sleep(10 * time.Millisecond)
+
+	delivered := make(chan *pdu.DeliverSm, 2)
+	responses := make(chan pdu.PDU, 2)
+	sess, err := smpp.BindTRx(smpp.SessionConf{
+		RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
+			if dsm, err := ctx.DeliverSm(); err == nil {
+				ctx.Respond(dsm.Response(""), pdu.StatusOK)
+				delivered <- dsm
+			}
+		}),
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {
+			switch ctx.CommandID() {
+			case pdu.SubmitSmRespID:
+				p, _ := ctx.SubmitSmResp()
+				responses <- p
+			case pdu.QuerySmRespID:
+				p, _ := ctx.QuerySmResp()
+				responses <- p
+			}
+		}),
+	}, smpp.BindConf{Addr: conf.Addr, SystemID: "esme", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = sess.SendRequest(context.Background(), &pdu.SubmitSm{
+		SourceAddr:         "111",
+		DestinationAddr:    "222",
+		ShortMessage:       "hello",
+		RegisteredDelivery: pdu.RegisteredDelivery{Receipt: pdu.YesDeliveryReceipt},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgID string
+	select {
+	case p := <-responses:
+		msgID = p.(*pdu.SubmitSmResp).MessageID
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for submit_sm_resp")
+	}
+	select {
+	case dsm := <-delivered:
+		if dsm.EsmClass.Type != pdu.DelRecEsmType {
+			t.Errorf("expected receipt esm_class got %+v", dsm.EsmClass)
+		}
+		if dsm.SourceAddr != "222" || dsm.DestinationAddr != "111" {
+			t.Errorf("receipt addresses are not swapped %s -> %s", dsm.SourceAddr, dsm.DestinationAddr)
+		}
+		dr, err := pdu.ParseDeliveryReceipt(dsm.ShortMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dr.Id != msgID || dr.Stat != pdu.DelStatUndeliverable || dr.Err != 11 {
+			t.Errorf("unexpected receipt %+v", dr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for receipt")
+	}
+
+	if _, err := sess.SendRequest(context.Background(), &pdu.QuerySm{MessageID: msgID}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case p := <-responses:
+		if st := p.(*pdu.QuerySmResp).MessageState; st != 5 {
+			t.Errorf("expected UNDELIVERABLE message state got %d", st)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for query_sm_resp")
+	}
+
+	long := strings.Repeat("a", 200)
+	moTT := []struct {
+		text   string
+		coding utility.DataCoding
+		texts  []string
+	}{
+		{"mo text", utility.DataCodingGSM7, []string{"mo text"}},
+		{"سلام", utility.DataCodingUCS2, []string{"سلام"}},
+		{long, utility.DataCodingGSM7, []string{long[:153], long[153:]}},
+	}
+	for _, tt := range moTT {
+		form := url.Values{"system_id": {"esme"}, "source": {"333"}, "destination": {"444"}, "text": {tt.text}}
+		req := httptest.NewRequest(http.MethodPost, "/mo", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		sim.ServeHTTP(rec, req)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected %d got %d %s", http.StatusAccepted, rec.Code, rec.Body)
+		}
+		// Handlers run concurrently, so parts may arrive in any order.
+		got := make([]string, len(tt.texts))
+		for range tt.texts {
+			select {
+			case dsm := <-delivered:
+				if dsm.SourceAddr != "333" || utility.DataCoding(dsm.DataCoding) != tt.coding {
+					t.Errorf("unexpected MO %+v", dsm)
+				}
+				ud, seq := []byte(dsm.ShortMessage), 1
+				if len(tt.texts) > 1 {
+					if dsm.EsmClass.Feature != pdu.UDHIEsmFeat {
+						t.Errorf("MO part of %q: UDHI not set %+v", tt.text, dsm.EsmClass)
+					}
+					var udh *utility.UserDataHeader
+					if udh, ud, err = utility.ParseUserDataHeader(ud); err != nil {
+						t.Fatal(err)
+					}
+					mpd, ok := udh.MultiPart()
+					if !ok || int(mpd.Total) != len(tt.texts) || mpd.Seq < 1 || int(mpd.Seq) > len(tt.texts) {
+						t.Fatalf("MO part of %q: unexpected UDH %+v", tt.text, udh)
+					}
+					seq = int(mpd.Seq)
+				}
+				body, err := utility.DecodeText(dsm.DataCoding, ud, 0, 0, utility.GSM7Strict)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[seq-1] = body
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for MO")
+			}
+		}
+		for i, want := range tt.texts {
+			if got[i] != want {
+				t.Errorf("MO part %d of %q => %q, expected %q", i+1, tt.text, got[i], want)
+			}
+		}
+	}
+}
@@ -79,7 +79,7 @@ func (ctx *Context) CloseSession() {
 func (ctx *Context) ForceClose() {
     ctx.close = true
 	// ctx.Sess.Close()
-    ctx.Sess.shutdown()
+    ctx.Sess.shutdown(CloseReasonHandler)
 }
 
 func (ctx *Context) Header() pdu.Header {
@@ -0,0 +1,317 @@
+package smpp
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+// IDFormat tells how an SMSC writes message IDs, so IDs from
+// submit_sm_resp and from receipts can be compared.
+type IDFormat int
+
+const (
+	// IDOpaque IDs are compared as they are.
+	IDOpaque IDFormat = iota
+	// IDDecimal IDs are decimal numbers, leading zeros are ignored.
+	IDDecimal
+	// IDHex IDs are hexadecimal numbers, case and leading zeros are ignored.
+	IDHex
+)
+
+// normalize returns id in the form used as correlation key. Numeric IDs
+// are keyed by their decimal value.
+func (f IDFormat) normalize(id string) string {
+	base := 10
+	switch f {
+	case IDDecimal:
+	case IDHex:
+		base = 16
+	default:
+		return id
+	}
+	n, err := strconv.ParseUint(id, base, 64)
+	if err != nil {
+		return id
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// Correlation is what a Correlator knows about a submitted message.
+type Correlation struct {
+	Ref       string // reference given to Track, empty for unmatched receipts
+	SessionID string
+	Seq       uint32
+	MessageID string               // normalized message_id
+	Status    pdu.Status           // command_status of the response
+	State     pdu.DeliveryStat     // last reported state, empty before any receipt
+	Receipt   *pdu.DeliveryReceipt // last receipt
+}
+
+// CorrelatorConf configures a Correlator.
+type CorrelatorConf struct {
+	// TTL after which a message that did not reach a final state is
+	// forgotten. It is restarted by the response. Defaults to 48 hours.
+	TTL time.Duration
+	// RespIDFormat and ReceiptIDFormat are the message ID formats of
+	// responses and receipts.
+	RespIDFormat    IDFormat
+	ReceiptIDFormat IDFormat
+	// OnFinal is called once per message when it reaches a final state,
+	// either through a receipt or through an error response, in which
+	// case State is REJECTD.
+	OnFinal func(Correlation)
+	// OnExpire is called when a message is forgotten after TTL. Receipts
+	// that never matched a submission expire the same way.
+	OnExpire func(Correlation)
+}
+
+// Correlator matches submissions to their submit_sm_resp by session and
+// sequence number, and then to their receipts by message ID. Responses
+// may be seen before Track and receipts before the response; both are
+// held until the other side arrives.
+//
+// Wrap the session handlers with WrapRequestHandler and
+// WrapResponseHandler, and call Track with the sequence number returned
+// by SendRequest.
+type Correlator struct {
+	conf   CorrelatorConf
+	mu     sync.Mutex
+	bySeq  map[correlationSeq]*correlationEntry
+	byID   map[string]*correlationEntry
+	closed bool
+}
+
+type correlationSeq struct {
+	sessionID string
+	seq       uint32
+}
+
+type correlationEntry struct {
+	c         Correlation
+	responded bool // response seen, waiting in bySeq for Track
+	early     bool // receipt seen, waiting in byID for the response
+	timer     *time.Timer
+}
+
+// NewCorrelator creates a Correlator.
+func NewCorrelator(conf CorrelatorConf) *Correlator {
+	if conf.TTL == 0 {
+		conf.TTL = 48 * time.Hour
+	}
+	return &Correlator{
+		conf:  conf,
+		bySeq: make(map[correlationSeq]*correlationEntry),
+		byID:  make(map[string]*correlationEntry),
+	}
+}
+
+// Track registers the request sent on sessionID with seq under ref.
+func (c *Correlator) Track(sessionID string, seq uint32, ref string) {
+	key := correlationSeq{sessionID, seq}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	e, ok := c.bySeq[key]
+	if !ok {
+		e = &correlationEntry{c: Correlation{Ref: ref, SessionID: sessionID, Seq: seq}}
+		c.bySeq[key] = e
+		c.expireAfter(e)
+		c.mu.Unlock()
+		return
+	}
+	delete(c.bySeq, key)
+	e.c.Ref = ref
+	final := c.matched(e)
+	c.mu.Unlock()
+	c.notify(final)
+}
+
+// Response records the response to the request sent on sessionID with seq.
+func (c *Correlator) Response(sessionID string, seq uint32, status pdu.Status, msgID string) {
+	key := correlationSeq{sessionID, seq}
+	msgID = c.conf.RespIDFormat.normalize(msgID)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	e, ok := c.bySeq[key]
+	if !ok || e.responded {
+		if ok {
+			e.timer.Stop()
+		}
+		e = &correlationEntry{
+			c:         Correlation{SessionID: sessionID, Seq: seq, MessageID: msgID, Status: status},
+			responded: true,
+		}
+		c.bySeq[key] = e
+		c.expireAfter(e)
+		c.mu.Unlock()
+		return
+	}
+	delete(c.bySeq, key)
+	e.c.MessageID, e.c.Status = msgID, status
+	final := c.matched(e)
+	c.mu.Unlock()
+	c.notify(final)
+}
+
+// Receipt records a delivery receipt.
+func (c *Correlator) Receipt(dr *pdu.DeliveryReceipt) {
+	id := c.conf.ReceiptIDFormat.normalize(dr.Id)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	e, ok := c.byID[id]
+	if !ok {
+		e = &correlationEntry{c: Correlation{MessageID: id}, early: true}
+		c.byID[id] = e
+		c.expireAfter(e)
+	}
+	e.c.State, e.c.Receipt = dr.Stat, dr
+	var final *Correlation
+	if !e.early && dr.Stat.Final() {
+		c.remove(e)
+		final = &e.c
+	}
+	c.mu.Unlock()
+	c.notify(final)
+}
+
+// Lookup returns what is known about the message the SMSC gave msgID, in
+// the format of responses.
+func (c *Correlator) Lookup(msgID string) (Correlation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.byID[c.conf.RespIDFormat.normalize(msgID)]
+	if !ok || e.early {
+		return Correlation{}, false
+	}
+	return e.c, true
+}
+
+// Len returns the number of messages and unmatched responses and receipts
+// held.
+func (c *Correlator) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.bySeq) + len(c.byID)
+}
+
+// Close drops everything held without calling OnExpire.
+func (c *Correlator) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.bySeq {
+		c.remove(e)
+	}
+	for _, e := range c.byID {
+		c.remove(e)
+	}
+	c.closed = true
+}
+
+// WrapRequestHandler returns a Handler passing receipts in deliver_sm and
+// data_sm to c before calling next.
+func (c *Correlator) WrapRequestHandler(next Handler) Handler {
+	return RequestHandlerFunc(func(ctx *Context) {
+		var (
+			dr  *pdu.DeliveryReceipt
+			err error
+		)
+		switch p := ctx.pdu.(type) {
+		case *pdu.DeliverSm:
+			dr, err = p.Receipt()
+		case *pdu.DataSm:
+			dr, err = p.Receipt()
+		}
+		if dr != nil && err == nil {
+			c.Receipt(dr)
+		}
+		next.ServeSMPP(ctx)
+	})
+}
+
+// WrapResponseHandler returns a Handler passing submit_sm_resp and
+// data_sm_resp to c before calling next.
+func (c *Correlator) WrapResponseHandler(next Handler) Handler {
+	return ResponseHandlerFunc(func(ctx *Context) {
+		switch p := ctx.pdu.(type) {
+		case *pdu.SubmitSmResp:
+			c.Response(ctx.SessionID(), ctx.Sequence(), ctx.Header().Status(), p.MessageID)
+		case *pdu.DataSmResp:
+			c.Response(ctx.SessionID(), ctx.Sequence(), ctx.Header().Status(), p.MessageID)
+		}
+		next.ServeSMPP(ctx)
+	})
+}
+
+// matched handles e once both Track and the response were seen. It
+// returns the correlation to pass to OnFinal, if any. It must be called
+// with c.mu held and e out of c.bySeq.
+func (c *Correlator) matched(e *correlationEntry) *Correlation {
+	e.timer.Stop()
+	e.responded = false
+	if e.c.Status != pdu.StatusOK {
+		e.c.State = pdu.DelStatRejected
+		return &e.c
+	}
+	if early, ok := c.byID[e.c.MessageID]; ok && early.early {
+		c.remove(early)
+		e.c.State, e.c.Receipt = early.c.State, early.c.Receipt
+		if e.c.State.Final() {
+			return &e.c
+		}
+	}
+	if old, ok := c.byID[e.c.MessageID]; ok {
+		c.remove(old)
+	}
+	c.byID[e.c.MessageID] = e
+	c.expireAfter(e)
+	return nil
+}
+
+// expireAfter must be called with c.mu held.
+func (c *Correlator) expireAfter(e *correlationEntry) {
+	e.timer = time.AfterFunc(c.conf.TTL, func() { c.expire(e) })
+}
+
+// remove must be called with c.mu held.
+func (c *Correlator) remove(e *correlationEntry) {
+	e.timer.Stop()
+	key := correlationSeq{e.c.SessionID, e.c.Seq}
+	if c.bySeq[key] == e {
+		delete(c.bySeq, key)
+	}
+	if c.byID[e.c.MessageID] == e {
+		delete(c.byID, e.c.MessageID)
+	}
+}
+
+func (c *Correlator) expire(e *correlationEntry) {
+	c.mu.Lock()
+	key := correlationSeq{e.c.SessionID, e.c.Seq}
+	if c.bySeq[key] != e && c.byID[e.c.MessageID] != e {
+		c.mu.Unlock()
+		return
+	}
+	c.remove(e)
+	corr := e.c
+	c.mu.Unlock()
+	if c.conf.OnExpire != nil {
+		c.conf.OnExpire(corr)
+	}
+}
+
+func (c *Correlator) notify(final *Correlation) {
+	if final != nil && c.conf.OnFinal != nil {
+		c.conf.OnFinal(*final)
+	}
+}
@@ -0,0 +1,129 @@
+package smpp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+type correlatorHeader struct {
+	id     pdu.CommandID
+	status pdu.Status
+	seq    uint32
+}
+
+func (h correlatorHeader) UnmarshalBinary([]byte) error { return nil }
+func (h correlatorHeader) Length() uint32               { return 0 }
+func (h correlatorHeader) CommandID() pdu.CommandID     { return h.id }
+func (h correlatorHeader) Status() pdu.Status           { return h.status }
+func (h correlatorHeader) Sequence() uint32             { return h.seq }
+
+func TestCorrelator(t *testing.T) {
+	var finals []Correlation
+	c := NewCorrelator(CorrelatorConf{
+		RespIDFormat:    IDHex,
+		ReceiptIDFormat: IDDecimal,
+		OnFinal:         func(corr Correlation) { finals = append(finals, corr) },
+	})
+	defer c.Close()
+
+	// In order: track, response, intermediate and final receipts.
+	c.Track("s1", 1, "a")
+	c.Response("s1", 1, pdu.StatusOK, "1A")
+	c.Receipt(&pdu.DeliveryReceipt{Id: "26", Stat: pdu.DelStatEnRoute})
+	if corr, ok := c.Lookup("001a"); !ok || corr.Ref != "a" || corr.State != pdu.DelStatEnRoute {
+		t.Fatalf("Lookup() => %+v, %v", corr, ok)
+	}
+	c.Receipt(&pdu.DeliveryReceipt{Id: "0026", Stat: pdu.DelStatDelivered})
+
+	// Response before Track, receipt before the response.
+	c.Response("s1", 2, pdu.StatusOK, "1B")
+	c.Track("s1", 2, "b")
+	c.Track("s2", 2, "c")
+	c.Receipt(&pdu.DeliveryReceipt{Id: "28", Stat: pdu.DelStatExpired})
+	c.Response("s2", 2, pdu.StatusOK, "1C")
+
+	// Error response.
+	c.Track("s1", 3, "d")
+	c.Response("s1", 3, pdu.StatusThrottled, "")
+
+	want := []struct {
+		ref   string
+		state pdu.DeliveryStat
+	}{
+		{"a", pdu.DelStatDelivered},
+		{"c", pdu.DelStatExpired},
+		{"d", pdu.DelStatRejected},
+	}
+	if len(finals) != len(want) {
+		t.Fatalf("OnFinal called %d times, expected %d: %+v", len(finals), len(want), finals)
+	}
+	for i, w := range want {
+		if finals[i].Ref != w.ref || finals[i].State != w.state {
+			t.Errorf("final %d => %+v, expected %s %s", i, finals[i], w.ref, w.state)
+		}
+	}
+	if corr, ok := c.Lookup("1B"); !ok || corr.Ref != "b" || corr.State != "" {
+		t.Errorf("Lookup() => %+v, %v", corr, ok)
+	}
+	if n := c.Len(); n != 1 {
+		t.Errorf("Len() => %d, expected 1", n)
+	}
+}
+
+func TestCorrelatorExpire(t *testing.T) {
+	expired := make(chan Correlation, 2)
+	c := NewCorrelator(CorrelatorConf{
+		TTL:      20 * time.Millisecond,
+		OnExpire: func(corr Correlation) { expired <- corr },
+	})
+	defer c.Close()
+	c.Track("s1", 1, "a")
+	c.Receipt(&pdu.DeliveryReceipt{Id: "x", Stat: pdu.DelStatDelivered})
+	for i := 0; i < 2; i++ {
+		select {
+		case corr := <-expired:
+			if corr.Ref != "a" && corr.MessageID != "x" {
+				t.Errorf("unexpected expiry %+v", corr)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("entries did not expire")
+		}
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len() => %d, expected 0", n)
+	}
+}
+
+func TestCorrelatorHandlers(t *testing.T) {
+	var final Correlation
+	c := NewCorrelator(CorrelatorConf{OnFinal: func(corr Correlation) { final = corr }})
+	defer c.Close()
+	sess := &Session{conf: &SessionConf{ID: "s1"}}
+	served := 0
+	next := RequestHandlerFunc(func(ctx *Context) { served++ })
+
+	c.Track("s1", 7, "a")
+	c.WrapResponseHandler(next).ServeSMPP(&Context{
+		Sess: sess,
+		seq:  7,
+		hdr:  correlatorHeader{id: pdu.SubmitSmRespID, seq: 7},
+		pdu:  &pdu.SubmitSmResp{MessageID: "m7"},
+	})
+	c.WrapRequestHandler(next).ServeSMPP(&Context{
+		Sess: sess,
+		seq:  1,
+		hdr:  correlatorHeader{id: pdu.DeliverSmID, seq: 1},
+		pdu: &pdu.DeliverSm{
+			EsmClass:     pdu.EsmClass{Type: pdu.DelRecEsmType},
+			ShortMessage: "id:m7 stat:UNDELIV err:001",
+		},
+	})
+	if served != 2 {
+		t.Errorf("next served %d times, expected 2", served)
+	}
+	if final.Ref != "a" || final.State != pdu.DelStatUndeliverable || final.Receipt.Err != 1 {
+		t.Errorf("OnFinal => %+v", final)
+	}
+}
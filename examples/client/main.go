@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/majiddarvishan/smpp"
 	"github.com/majiddarvishan/smpp/pdu"
@@ -28,27 +29,50 @@ func main() {
 		Addr:     serverAddr,
 		SystemID: "ExampleClient",
 	}
-	sc := smpp.SessionConf{}
+	// Responses are delivered to the response handler.
+	resps := make(chan *smpp.Context, 1)
+	sc := smpp.SessionConf{
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {
+			resps <- ctx
+		}),
+	}
 	sess, err := smpp.BindTRx(sc, bc)
 	if err != nil {
 		fail("Can't bind: %v", err)
 	}
+	if resp := waitResponse(resps); resp.Header().Status() != pdu.StatusOK {
+		fail("Can't bind: %s", resp.Header().Status())
+	}
 	sm := &pdu.SubmitSm{
 		SourceAddr:      srcAddr,
 		DestinationAddr: dstAddr,
 		ShortMessage:    msg,
 	}
-	_, resp, err := sess.Send(context.Background(), sm)
-	if err != nil {
+	if _, err := sess.SendRequest(context.Background(), sm); err != nil {
 		fail("Can't send message: %+v", err)
 	}
 	fmt.Fprintf(os.Stderr, "Message sent\n")
-	fmt.Fprintf(os.Stderr, "Received response %s %+v\n", resp.CommandID(), resp)
+	resp := waitResponse(resps)
+	smResp, err := resp.SubmitSmResp()
+	if err != nil {
+		fail("Unexpected response: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Received response %s %s %+v\n", resp.CommandID(), resp.Header().Status(), smResp)
 	if err := smpp.Unbind(context.Background(), sess); err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 	}
 }
 
+func waitResponse(resps <-chan *smpp.Context) *smpp.Context {
+	select {
+	case resp := <-resps:
+		return resp
+	case <-time.After(5 * time.Second):
+		fail("Timeout waiting for response")
+	}
+	return nil
+}
+
 func fail(msg string, params ...interface{}) {
 	fmt.Fprintf(os.Stderr, msg+"\n", params...)
 	os.Exit(1)
@@ -2,7 +2,4 @@ module github.com/majiddarvishan/smpp
 
 go 1.14
 
-require (
-	github.com/abadojack/whatlanggo v1.0.1
-	github.com/stretchr/testify v1.10.0
-)
+require github.com/stretchr/testify v1.10.0
@@ -122,7 +122,8 @@ func TestConnWait(t *testing.T) {
 			r := make([]byte, 1)
 			n, err := c.Read(r)
 			if err != nil || n != 1 {
-				t.Fatalf("Invalid read results %d %v", n, err)
+				t.Errorf("Invalid read results %d %v", n, err)
+				return
 			}
 			out[i] = r[0]
 			sync <- struct{}{}
@@ -166,7 +167,8 @@ func TestConnWaitWriteFirst(t *testing.T) {
 		for i := range write {
 			n, err := c.Write([]byte{write[i]})
 			if err != nil || n != 1 {
-				t.Fatalf("Invalid Write results %d %v", n, err)
+				t.Errorf("Invalid Write results %d %v", n, err)
+				return
 			}
 			sync <- struct{}{}
 		}
@@ -205,7 +207,8 @@ func TestConnProcessWrites(t *testing.T) {
 		for i := 0; i < 10; i++ {
 			_, err := c.Write([]byte{byte(i + 1)})
 			if err != nil {
-				t.Fatal(err)
+				t.Error(err)
+				return
 			}
 		}
 		sync <- struct{}{}
@@ -215,7 +218,8 @@ func TestConnProcessWrites(t *testing.T) {
 			out := make([]byte, 1)
 			_, err := c.Read(out)
 			if err != nil {
-				t.Fatal(err)
+				t.Error(err)
+				return
 			}
 		}
 		sync <- struct{}{}
@@ -0,0 +1,144 @@
+package smpp
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+// LogLevel is the severity of a log entry.
+type LogLevel int
+
+const (
+	// LevelTrace entries carry PDU bodies.
+	LevelTrace LogLevel = iota - 2
+	// LevelDebug entries report PDUs sent and received and state changes.
+	LevelDebug
+	// LevelInfo entries report the session lifecycle: timeouts, closing.
+	LevelInfo
+	// LevelError entries report failures.
+	LevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelError:
+		return "ERROR"
+	}
+	return "LogLevel(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Field is a key/value pair attached to a log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Keys of the fields sessions attach to their log entries. Every entry has
+// FieldSessionID and FieldSystemID.
+const (
+	FieldSessionID = "session_id"
+	FieldSystemID  = "system_id"
+	FieldSeq       = "seq"
+	FieldCommand   = "command"
+	FieldStatus    = "status"
+	FieldPDU       = "pdu"
+	FieldState     = "state"
+	FieldReason    = "reason"
+	FieldError     = "error"
+)
+
+// StructuredLogger receives leveled log entries with fields.
+type StructuredLogger interface {
+	// Enabled reports whether entries at level are logged, so costly
+	// fields are only built when needed.
+	Enabled(level LogLevel) bool
+	Log(level LogLevel, msg string, fields ...Field)
+}
+
+// NewPrintfLogger returns a StructuredLogger passing entries at level and
+// above to l, with the fields appended to msg as key=value. Error entries go
+// to ErrorF, the others to InfoF.
+func NewPrintfLogger(l Logger, level LogLevel) StructuredLogger {
+	return printfLogger{l: l, level: level}
+}
+
+type printfLogger struct {
+	l     Logger
+	level LogLevel
+}
+
+func (pl printfLogger) Enabled(level LogLevel) bool {
+	return level >= pl.level
+}
+
+func (pl printfLogger) Log(level LogLevel, msg string, fields ...Field) {
+	if !pl.Enabled(level) {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for _, f := range fields {
+		sb.WriteString(" ")
+		sb.WriteString(f.Key)
+		sb.WriteString("=")
+		v := fmt.Sprint(f.Value)
+		if strings.ContainsAny(v, " \t\r\n\"=") || v == "" {
+			v = strconv.Quote(v)
+		}
+		sb.WriteString(v)
+	}
+	if level >= LevelError {
+		pl.l.ErrorF("%s", sb.String())
+		return
+	}
+	pl.l.InfoF("%s", sb.String())
+}
+
+// logAt logs msg with the session fields followed by fields.
+func (sess *Session) logAt(level LogLevel, msg string, fields ...Field) {
+	l := sess.conf.StructuredLogger
+	if !l.Enabled(level) {
+		return
+	}
+	all := make([]Field, 0, 2+len(fields))
+	all = append(all, Field{FieldSessionID, sess.conf.ID}, Field{FieldSystemID, sess.SystemID()})
+	l.Log(level, msg, append(all, fields...)...)
+}
+
+// logPDU logs p at debug level, adding its body if trace is enabled. The
+// body hides passwords and message content unless LogSensitive is set.
+func (sess *Session) logPDU(msg string, seq uint32, status pdu.Status, p pdu.PDU) {
+	l := sess.conf.StructuredLogger
+	if !l.Enabled(LevelDebug) {
+		return
+	}
+	fields := []Field{{FieldCommand, p.CommandID()}, {FieldSeq, seq}, {FieldStatus, status}}
+	if l.Enabled(LevelTrace) {
+		body := fmt.Sprint(p)
+		if sess.conf.LogSensitive {
+			body = pdu.Unredacted(p).String()
+		}
+		fields = append(fields, Field{FieldPDU, body})
+	}
+	sess.logAt(LevelDebug, msg, fields...)
+}
+
+// logReadError logs the error ending serve, at info level if the peer
+// closed the connection.
+func (sess *Session) logReadError(err error) {
+	if err == io.EOF {
+		sess.logAt(LevelInfo, "connection closed by peer")
+		return
+	}
+	sess.logAt(LevelError, "reading pdu", Field{FieldError, err})
+}
@@ -0,0 +1,142 @@
+package smpp
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+// runLoggedBind serves a bind_transmitter from "esme" with password
+// "secret" on an SMSC session configured with conf, then closes it.
+func runLoggedBind(t *testing.T, conf SessionConf) {
+	t.Helper()
+	local, remote := net.Pipe()
+	conf.Type = SMSC
+	conf.ID = "s1"
+	conf.RequestHandler = RequestHandlerFunc(func(ctx *Context) {
+		bind, err := ctx.BindTx()
+		if err != nil {
+			t.Errorf("BindTx() error %v", err)
+			return
+		}
+		if err := ctx.Respond(bind.Response("smsc"), pdu.StatusOK); err != nil {
+			t.Errorf("Respond() error %v", err)
+		}
+	})
+	sess := NewSession(local, conf)
+
+	enc := pdu.NewEncoder(pdu.NewSequencer(1))
+	_, buf, err := enc.Encode(&pdu.BindTx{SystemID: "esme", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	var hdr [16]byte
+	if _, err := io.ReadFull(remote, hdr[:]); err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, int(hdr[3])-16)
+	if _, err := io.ReadFull(remote, body); err != nil {
+		t.Fatal(err)
+	}
+	remote.Close()
+	select {
+	case <-sess.NotifyClosed():
+	case <-time.After(time.Second):
+		t.Fatal("session did not close")
+	}
+}
+
+type recordedLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordedLogger) InfoF(msg string, params ...interface{}) {
+	l.mu.Lock()
+	l.lines = append(l.lines, "INFO "+fmt.Sprintf(msg, params...))
+	l.mu.Unlock()
+}
+
+func (l *recordedLogger) ErrorF(msg string, params ...interface{}) {
+	l.mu.Lock()
+	l.lines = append(l.lines, "ERROR "+fmt.Sprintf(msg, params...))
+	l.mu.Unlock()
+}
+
+func (l *recordedLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.lines, "\n")
+}
+
+func TestPrintfLogger(t *testing.T) {
+	rec := &recordedLogger{}
+	runLoggedBind(t, SessionConf{Logger: rec})
+	if out := rec.String(); out != "INFO connection closed by peer session_id=s1 system_id=esme\n"+
+		"INFO session closed session_id=s1 system_id=esme reason=CloseReasonConnection" {
+		t.Errorf("default logging =>\n%s", out)
+	}
+
+	rec = &recordedLogger{}
+	l := NewPrintfLogger(rec, LevelError)
+	l.Log(LevelInfo, "dropped")
+	l.Log(LevelError, "kept 100%", Field{"a", "x y"}, Field{"b", ""}, Field{"c", 1})
+	if out := rec.String(); out != `ERROR kept 100% a="x y" b="" c=1` {
+		t.Errorf("Log() => %s", out)
+	}
+}
+
+func TestLogRedaction(t *testing.T) {
+	for _, sensitive := range []bool{false, true} {
+		rec := &recordedLogger{}
+		runLoggedBind(t, SessionConf{
+			StructuredLogger: NewPrintfLogger(rec, LevelTrace),
+			LogSensitive:     sensitive,
+		})
+		out := rec.String()
+		if !strings.Contains(out, "INFO received request session_id=s1 system_id=esme command=BindTransmitterID seq=1 status=StatusOK pdu=") {
+			t.Errorf("LogSensitive %v: no received request entry:\n%s", sensitive, out)
+		}
+		if strings.Contains(out, "secret") != sensitive {
+			t.Errorf("LogSensitive %v: password logged %v:\n%s", sensitive, !sensitive, out)
+		}
+	}
+}
+
+func TestLogTimeoutDuringBind(t *testing.T) {
+	enc := pdu.NewEncoder(pdu.NewSequencer(1))
+	_, buf, err := enc.Encode(&pdu.BindTx{SystemID: "esme", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Timeouts fire while serve stores the system_id of the bind, their log
+	// entries must not race with it.
+	for i := 0; i < 20; i++ {
+		local, remote := net.Pipe()
+		sess := NewSession(local, SessionConf{
+			Type:              SMSC,
+			BindTimeout:       5 * time.Millisecond,
+			InactivityTimeout: 5 * time.Millisecond,
+			StructuredLogger:  NewPrintfLogger(&recordedLogger{}, LevelInfo),
+			// Never answer, so the session is still binding on timeout.
+			RequestHandler: RequestHandlerFunc(func(ctx *Context) {}),
+		})
+		time.Sleep(time.Duration(i) * time.Millisecond / 2)
+		remote.Write(buf)
+		select {
+		case <-sess.NotifyClosed():
+		case <-time.After(time.Second):
+			t.Fatal("session did not close")
+		}
+		remote.Close()
+	}
+}
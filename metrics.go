@@ -0,0 +1,35 @@
+package smpp
+
+import (
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+// Metrics receives measurements from sessions. Server sessions report to
+// the Metrics of the server's SessionConf. Implementations must be safe
+// for concurrent use and return quickly, some methods are called with the
+// session lock held.
+type Metrics interface {
+	// SessionOpened and SessionClosed mark the lifetime of a session.
+	SessionOpened(sessionID string, typ SessionType)
+	SessionClosed(sessionID string, typ SessionType, reason CloseReason)
+	// PDUSent and PDUReceived count PDUs by command_id and command_status.
+	PDUSent(id pdu.CommandID, status pdu.Status)
+	PDUReceived(id pdu.CommandID, status pdu.Status)
+	// ResponseLatency observes the time between sending request id and
+	// receiving its response with status.
+	ResponseLatency(id pdu.CommandID, status pdu.Status, d time.Duration)
+	// Windows reports the occupancy of the session windows: requests sent
+	// waiting for a response, and requests received being handled.
+	Windows(sessionID string, send, recv int)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) SessionOpened(string, SessionType)                        {}
+func (nopMetrics) SessionClosed(string, SessionType, CloseReason)           {}
+func (nopMetrics) PDUSent(pdu.CommandID, pdu.Status)                        {}
+func (nopMetrics) PDUReceived(pdu.CommandID, pdu.Status)                    {}
+func (nopMetrics) ResponseLatency(pdu.CommandID, pdu.Status, time.Duration) {}
+func (nopMetrics) Windows(string, int, int)                                 {}
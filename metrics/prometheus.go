@@ -0,0 +1,260 @@
+// Package metrics collects smpp.Metrics in memory and exposes them in the
+// Prometheus text exposition format, without depending on the Prometheus
+// client libraries.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/majiddarvishan/smpp"
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+// DefaultBuckets are the latency histogram upper bounds in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Prometheus implements smpp.Metrics and serves what it collected:
+//
+//	smpp_sessions_open{type}                        gauge
+//	smpp_sessions_closed_total{type,reason}         counter
+//	smpp_pdus_sent_total{command,status}            counter
+//	smpp_pdus_received_total{command,status}        counter
+//	smpp_response_latency_seconds{command,status}   histogram
+//	smpp_send_window{session}                       gauge
+//	smpp_receive_window{session}                    gauge
+type Prometheus struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	open     map[string]int
+	closed   map[[2]string]uint64
+	sent     map[[2]string]uint64
+	received map[[2]string]uint64
+	latency  map[[2]string]*histogram
+	sendWin  map[string]int
+	recvWin  map[string]int
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+var _ smpp.Metrics = (*Prometheus)(nil)
+
+// NewPrometheus creates a collector with the given latency buckets, in
+// seconds and increasing. It uses DefaultBuckets when none are given.
+func NewPrometheus(buckets ...float64) *Prometheus {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Prometheus{
+		buckets:  buckets,
+		open:     make(map[string]int),
+		closed:   make(map[[2]string]uint64),
+		sent:     make(map[[2]string]uint64),
+		received: make(map[[2]string]uint64),
+		latency:  make(map[[2]string]*histogram),
+		sendWin:  make(map[string]int),
+		recvWin:  make(map[string]int),
+	}
+}
+
+// SessionOpened implements smpp.Metrics.
+func (p *Prometheus) SessionOpened(sessionID string, typ smpp.SessionType) {
+	p.mu.Lock()
+	p.open[typ.String()]++
+	p.mu.Unlock()
+}
+
+// SessionClosed implements smpp.Metrics.
+func (p *Prometheus) SessionClosed(sessionID string, typ smpp.SessionType, reason smpp.CloseReason) {
+	p.mu.Lock()
+	p.open[typ.String()]--
+	p.closed[[2]string{typ.String(), reason.String()}]++
+	delete(p.sendWin, sessionID)
+	delete(p.recvWin, sessionID)
+	p.mu.Unlock()
+}
+
+// PDUSent implements smpp.Metrics.
+func (p *Prometheus) PDUSent(id pdu.CommandID, status pdu.Status) {
+	p.mu.Lock()
+	p.sent[pduLabels(id, status)]++
+	p.mu.Unlock()
+}
+
+// PDUReceived implements smpp.Metrics.
+func (p *Prometheus) PDUReceived(id pdu.CommandID, status pdu.Status) {
+	p.mu.Lock()
+	p.received[pduLabels(id, status)]++
+	p.mu.Unlock()
+}
+
+// ResponseLatency implements smpp.Metrics.
+func (p *Prometheus) ResponseLatency(id pdu.CommandID, status pdu.Status, d time.Duration) {
+	key := pduLabels(id, status)
+	v := d.Seconds()
+	p.mu.Lock()
+	h, ok := p.latency[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(p.buckets))}
+		p.latency[key] = h
+	}
+	if i := sort.SearchFloat64s(p.buckets, v); i < len(p.buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+	p.mu.Unlock()
+}
+
+// Windows implements smpp.Metrics.
+func (p *Prometheus) Windows(sessionID string, send, recv int) {
+	p.mu.Lock()
+	p.sendWin[sessionID] = send
+	p.recvWin[sessionID] = recv
+	p.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics in the text exposition format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = p.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the text exposition format.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	p.mu.Lock()
+	p.write(cw)
+	p.mu.Unlock()
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func (p *Prometheus) write(w *countingWriter) {
+	w.header("smpp_sessions_open", "gauge", "Sessions currently open.")
+	for _, typ := range sortedKeys(p.open) {
+		w.sample("smpp_sessions_open", labels("type", typ), float64(p.open[typ]))
+	}
+	w.header("smpp_sessions_closed_total", "counter", "Sessions closed.")
+	for _, k := range sortedPairs(p.closed) {
+		w.sample("smpp_sessions_closed_total", labels("type", k[0], "reason", k[1]), float64(p.closed[k]))
+	}
+	w.header("smpp_pdus_sent_total", "counter", "PDUs sent.")
+	for _, k := range sortedPairs(p.sent) {
+		w.sample("smpp_pdus_sent_total", labels("command", k[0], "status", k[1]), float64(p.sent[k]))
+	}
+	w.header("smpp_pdus_received_total", "counter", "PDUs received.")
+	for _, k := range sortedPairs(p.received) {
+		w.sample("smpp_pdus_received_total", labels("command", k[0], "status", k[1]), float64(p.received[k]))
+	}
+	w.header("smpp_response_latency_seconds", "histogram", "Time from sending a request to receiving its response.")
+	for _, k := range sortedPairs(p.latency) {
+		h := p.latency[k]
+		var cum uint64
+		for i, le := range p.buckets {
+			cum += h.counts[i]
+			w.sample("smpp_response_latency_seconds_bucket",
+				labels("command", k[0], "status", k[1], "le", strconv.FormatFloat(le, 'g', -1, 64)), float64(cum))
+		}
+		w.sample("smpp_response_latency_seconds_bucket", labels("command", k[0], "status", k[1], "le", "+Inf"), float64(h.count))
+		w.sample("smpp_response_latency_seconds_sum", labels("command", k[0], "status", k[1]), h.sum)
+		w.sample("smpp_response_latency_seconds_count", labels("command", k[0], "status", k[1]), float64(h.count))
+	}
+	w.header("smpp_send_window", "gauge", "Requests sent waiting for a response.")
+	for _, id := range sortedKeys(p.sendWin) {
+		w.sample("smpp_send_window", labels("session", id), float64(p.sendWin[id]))
+	}
+	w.header("smpp_receive_window", "gauge", "Requests received being handled.")
+	for _, id := range sortedKeys(p.recvWin) {
+		w.sample("smpp_receive_window", labels("session", id), float64(p.recvWin[id]))
+	}
+}
+
+func pduLabels(id pdu.CommandID, status pdu.Status) [2]string {
+	return [2]string{strings.TrimSuffix(id.String(), "ID"), status.String()}
+}
+
+func labels(kv ...string) string {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(kv[i])
+		sb.WriteString(`="`)
+		sb.WriteString(labelEscaper.Replace(kv[i+1]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedPairs(m interface{}) [][2]string {
+	var keys [][2]string
+	switch m := m.(type) {
+	case map[[2]string]uint64:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[[2]string]*histogram:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) printf(format string, args ...interface{}) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func (cw *countingWriter) header(name, typ, help string) {
+	cw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (cw *countingWriter) sample(name, labels string, v float64) {
+	cw.printf("%s%s %s\n", name, labels, strconv.FormatFloat(v, 'g', -1, 64))
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/majiddarvishan/smpp"
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus(0.1, 1)
+	p.SessionOpened("a", smpp.ESME)
+	p.SessionOpened("b", smpp.ESME)
+	p.Windows("b", 1, 0)
+	p.SessionClosed("b", smpp.ESME, smpp.CloseReasonUnbind)
+	p.PDUSent(pdu.SubmitSmID, pdu.StatusOK)
+	p.PDUSent(pdu.SubmitSmID, pdu.StatusOK)
+	p.PDUReceived(pdu.SubmitSmRespID, pdu.StatusThrottled)
+	p.ResponseLatency(pdu.SubmitSmID, pdu.StatusOK, 50*time.Millisecond)
+	p.ResponseLatency(pdu.SubmitSmID, pdu.StatusOK, 500*time.Millisecond)
+	p.ResponseLatency(pdu.SubmitSmID, pdu.StatusOK, 5*time.Second)
+	p.Windows("a", 3, 1)
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+	status := pdu.StatusOK.String()
+	for _, line := range []string{
+		"# TYPE smpp_sessions_open gauge",
+		`smpp_sessions_open{type="ESME"} 1`,
+		`smpp_sessions_closed_total{type="ESME",reason="CloseReasonUnbind"} 1`,
+		`smpp_pdus_sent_total{command="SubmitSm",status="` + status + `"} 2`,
+		`smpp_pdus_received_total{command="SubmitSmResp",status="` + pdu.StatusThrottled.String() + `"} 1`,
+		"# TYPE smpp_response_latency_seconds histogram",
+		`smpp_response_latency_seconds_bucket{command="SubmitSm",status="` + status + `",le="0.1"} 1`,
+		`smpp_response_latency_seconds_bucket{command="SubmitSm",status="` + status + `",le="1"} 2`,
+		`smpp_response_latency_seconds_bucket{command="SubmitSm",status="` + status + `",le="+Inf"} 3`,
+		`smpp_response_latency_seconds_sum{command="SubmitSm",status="` + status + `"} 5.55`,
+		`smpp_response_latency_seconds_count{command="SubmitSm",status="` + status + `"} 3`,
+		`smpp_send_window{session="a"} 3`,
+		`smpp_receive_window{session="a"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("output misses %q:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, `session="b"`) {
+		t.Errorf("closed session b still reported:\n%s", out)
+	}
+}
@@ -0,0 +1,126 @@
+package smpp
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+type recordedMetrics struct {
+	mu       sync.Mutex
+	opened   int
+	closed   []CloseReason
+	sent     []pdu.CommandID
+	received []pdu.CommandID
+	latency  []pdu.CommandID
+	maxRecv  int
+	windows  int
+}
+
+func (m *recordedMetrics) SessionOpened(string, SessionType) {
+	m.mu.Lock()
+	m.opened++
+	m.mu.Unlock()
+}
+
+func (m *recordedMetrics) SessionClosed(_ string, _ SessionType, reason CloseReason) {
+	m.mu.Lock()
+	m.closed = append(m.closed, reason)
+	m.mu.Unlock()
+}
+
+func (m *recordedMetrics) PDUSent(id pdu.CommandID, _ pdu.Status) {
+	m.mu.Lock()
+	m.sent = append(m.sent, id)
+	m.mu.Unlock()
+}
+
+func (m *recordedMetrics) PDUReceived(id pdu.CommandID, _ pdu.Status) {
+	m.mu.Lock()
+	m.received = append(m.received, id)
+	m.mu.Unlock()
+}
+
+func (m *recordedMetrics) ResponseLatency(id pdu.CommandID, _ pdu.Status, _ time.Duration) {
+	m.mu.Lock()
+	m.latency = append(m.latency, id)
+	m.mu.Unlock()
+}
+
+func (m *recordedMetrics) Windows(_ string, _, recv int) {
+	m.mu.Lock()
+	m.windows++
+	if recv > m.maxRecv {
+		m.maxRecv = recv
+	}
+	m.mu.Unlock()
+}
+
+func TestSessionMetrics(t *testing.T) {
+	local, remote := net.Pipe()
+	m := &recordedMetrics{}
+	sess := NewSession(local, SessionConf{
+		Type:    SMSC,
+		Metrics: m,
+		RequestHandler: RequestHandlerFunc(func(ctx *Context) {
+			bind, err := ctx.BindTx()
+			if err != nil {
+				t.Errorf("BindTx() error %v", err)
+				return
+			}
+			if err := ctx.Respond(bind.Response("smsc"), pdu.StatusOK); err != nil {
+				t.Errorf("Respond() error %v", err)
+			}
+		}),
+	})
+
+	enc := pdu.NewEncoder(pdu.NewSequencer(1))
+	_, buf, err := enc.Encode(&pdu.BindTx{SystemID: "esme", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	var hdr [16]byte
+	if _, err := io.ReadFull(remote, hdr[:]); err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, int(hdr[3])-16)
+	if _, err := io.ReadFull(remote, body); err != nil {
+		t.Fatal(err)
+	}
+	remote.Close()
+	select {
+	case <-sess.NotifyClosed():
+	case <-time.After(time.Second):
+		t.Fatal("session did not close")
+	}
+
+	m.mu.Lock()
+	windows := m.windows
+	m.mu.Unlock()
+	sess.ReleaseSequenceNumber(1)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.windows != windows {
+		t.Error("windows reported after the session closed")
+	}
+	if m.opened != 1 || len(m.closed) != 1 || m.closed[0] != CloseReasonConnection {
+		t.Errorf("opened %d closed %v", m.opened, m.closed)
+	}
+	if len(m.received) != 1 || m.received[0] != pdu.BindTransmitterID {
+		t.Errorf("received %v", m.received)
+	}
+	if len(m.sent) != 1 || m.sent[0] != pdu.BindTransmitterRespID {
+		t.Errorf("sent %v", m.sent)
+	}
+	if m.maxRecv != 1 {
+		t.Errorf("receive window peaked at %d, expected 1", m.maxRecv)
+	}
+}
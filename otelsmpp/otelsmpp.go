@@ -0,0 +1,89 @@
+// Package otelsmpp reports the spans of smpp sessions to OpenTelemetry.
+//
+// It is a separate module so that the smpp module does not depend on
+// OpenTelemetry.
+package otelsmpp
+
+import (
+	"context"
+
+	"github.com/majiddarvishan/smpp"
+	"github.com/majiddarvishan/smpp/pdu"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans.
+const ScopeName = "github.com/majiddarvishan/smpp/otelsmpp"
+
+// Attribute keys set on spans.
+const (
+	SessionIDKey     = attribute.Key("smpp.session_id")
+	SystemIDKey      = attribute.Key("smpp.system_id")
+	CommandKey       = attribute.Key("smpp.command")
+	SequenceKey      = attribute.Key("smpp.sequence")
+	CommandStatusKey = attribute.Key("smpp.command_status")
+)
+
+// Tracer implements smpp.Tracer by starting an OpenTelemetry span per
+// smpp span, named after its kind.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a Tracer creating spans with tp, or with the global
+// TracerProvider if tp is nil.
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: tp.Tracer(ScopeName)}
+}
+
+// StartSpan implements smpp.Tracer.
+func (t *Tracer) StartSpan(ctx context.Context, span smpp.Span) context.Context {
+	attrs := []attribute.KeyValue{
+		SessionIDKey.String(span.SessionID),
+		SystemIDKey.String(span.SystemID),
+		CommandKey.String(span.CommandID.String()),
+	}
+	if span.Seq != 0 {
+		attrs = append(attrs, SequenceKey.Int64(int64(span.Seq)))
+	}
+	ctx, _ = t.tracer.Start(ctx, span.Kind.String(),
+		trace.WithTimestamp(span.Start),
+		trace.WithSpanKind(spanKind(span.Kind)),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+// EndSpan implements smpp.Tracer. Spans with an error or a command_status
+// other than ESME_ROK get the error status.
+func (t *Tracer) EndSpan(ctx context.Context, span smpp.Span) {
+	s := trace.SpanFromContext(ctx)
+	s.SetAttributes(
+		SequenceKey.Int64(int64(span.Seq)),
+		CommandStatusKey.Int64(int64(span.Status)),
+	)
+	switch {
+	case span.Err != nil:
+		s.RecordError(span.Err)
+		s.SetStatus(codes.Error, span.Err.Error())
+	case span.Status != pdu.StatusOK:
+		s.SetStatus(codes.Error, span.Status.String())
+	}
+	s.End(trace.WithTimestamp(span.Start.Add(span.Duration)))
+}
+
+func spanKind(k smpp.SpanKind) trace.SpanKind {
+	switch k {
+	case smpp.SpanSend:
+		return trace.SpanKindProducer
+	case smpp.SpanReceive:
+		return trace.SpanKindConsumer
+	}
+	return trace.SpanKindInternal
+}
@@ -0,0 +1,61 @@
+package otelsmpp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/majiddarvishan/smpp"
+	"github.com/majiddarvishan/smpp/pdu"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tr := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	start := time.Now()
+	send := smpp.Span{Kind: smpp.SpanSend, SessionID: "s1", SystemID: "esme", CommandID: pdu.SubmitSmID, Start: start}
+	sendCtx := tr.StartSpan(context.Background(), send)
+	recv := smpp.Span{Kind: smpp.SpanReceive, SessionID: "s1", SystemID: "esme", CommandID: pdu.SubmitSmRespID, Seq: 7, Start: start.Add(time.Millisecond)}
+	recvCtx := tr.StartSpan(sendCtx, recv)
+	recv.Status, recv.Duration = pdu.StatusThrottled, time.Millisecond
+	tr.EndSpan(recvCtx, recv)
+	send.Seq, send.Err, send.Duration = 7, errors.New("boom"), 3*time.Millisecond
+	tr.EndSpan(sendCtx, send)
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans ended, expected 2", len(spans))
+	}
+	r, s := spans[0], spans[1]
+	if r.Name() != "smpp.receive" || r.SpanKind() != trace.SpanKindConsumer || r.Parent().SpanID() != s.SpanContext().SpanID() {
+		t.Errorf("receive span %s %s parent %s", r.Name(), r.SpanKind(), r.Parent().SpanID())
+	}
+	if r.Status().Code != codes.Error || r.Status().Description != pdu.StatusThrottled.String() {
+		t.Errorf("receive span status %+v", r.Status())
+	}
+	if s.Name() != "smpp.send" || s.Status().Code != codes.Error || s.EndTime().Sub(s.StartTime()) != 3*time.Millisecond {
+		t.Errorf("send span %s status %+v duration %s", s.Name(), s.Status(), s.EndTime().Sub(s.StartTime()))
+	}
+	want := map[string]string{
+		string(SessionIDKey):     "s1",
+		string(SystemIDKey):      "esme",
+		string(CommandKey):       "SubmitSmID",
+		string(SequenceKey):      "7",
+		string(CommandStatusKey): "0",
+	}
+	for _, kv := range s.Attributes() {
+		if w, ok := want[string(kv.Key)]; ok && kv.Value.Emit() != w {
+			t.Errorf("attribute %s => %s, expected %s", kv.Key, kv.Value.Emit(), w)
+		}
+		delete(want, string(kv.Key))
+	}
+	if len(want) != 0 {
+		t.Errorf("missing attributes %v", want)
+	}
+}
@@ -59,7 +59,7 @@ func (p *BindTx) String() string {
     var sb strings.Builder
     sb.WriteString("{\n")
     sb.WriteString(fmt.Sprintf(" SystemID: %s\n", p.SystemID))
-    sb.WriteString(fmt.Sprintf(" Password: %s\n", p.Password))
+    sb.WriteString(fmt.Sprintf(" Password: %s\n", redact(p.Password)))
     sb.WriteString(fmt.Sprintf(" SystemType: %s\n", p.SystemType))
     sb.WriteString(fmt.Sprintf(" InterfaceVersion: %d\n", p.InterfaceVersion))
     sb.WriteString(fmt.Sprintf(" AddrTon: %d\n", p.AddrTon))
@@ -154,7 +154,7 @@ func (p *BindRx) String() string {
     var sb strings.Builder
     sb.WriteString("{\n")
     sb.WriteString(fmt.Sprintf(" SystemID: %s\n", p.SystemID))
-    sb.WriteString(fmt.Sprintf(" Password: %s\n", p.Password))
+    sb.WriteString(fmt.Sprintf(" Password: %s\n", redact(p.Password)))
     sb.WriteString(fmt.Sprintf(" SystemType: %s\n", p.SystemType))
     sb.WriteString(fmt.Sprintf(" InterfaceVersion: %d\n", p.InterfaceVersion))
     sb.WriteString(fmt.Sprintf(" AddrTon: %d\n", p.AddrTon))
@@ -249,7 +249,7 @@ func (p *BindTRx) String() string {
     var sb strings.Builder
     sb.WriteString("{\n")
     sb.WriteString(fmt.Sprintf(" SystemID: %s\n", p.SystemID))
-    sb.WriteString(fmt.Sprintf(" Password: %s\n", p.Password))
+    sb.WriteString(fmt.Sprintf(" Password: %s\n", redact(p.Password)))
     sb.WriteString(fmt.Sprintf(" SystemType: %s\n", p.SystemType))
     sb.WriteString(fmt.Sprintf(" InterfaceVersion: %d\n", p.InterfaceVersion))
     sb.WriteString(fmt.Sprintf(" AddrTon: %d\n", p.AddrTon))
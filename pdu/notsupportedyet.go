@@ -4,36 +4,6 @@ import (
 	"fmt"
 )
 
-// ReplaceSm Not supported yet.
-type ReplaceSm struct{}
-
-func (p ReplaceSm) CommandID() CommandID {
-	return ReplaceSmID
-}
-
-func (p ReplaceSm) MarshalBinary() ([]byte, error) {
-	return nil, fmt.Errorf("Command %s is not supported yet", p.CommandID())
-}
-
-func (p *ReplaceSm) UnmarshalBinary(body []byte) error {
-	return fmt.Errorf("Command %s is not supported yet", p.CommandID())
-}
-
-// ReplaceSmResp Not supported yet.
-type ReplaceSmResp struct{}
-
-func (p ReplaceSmResp) CommandID() CommandID {
-	return ReplaceSmRespID
-}
-
-func (p ReplaceSmResp) MarshalBinary() ([]byte, error) {
-	return nil, fmt.Errorf("Command %s is not supported yet", p.CommandID())
-}
-
-func (p *ReplaceSmResp) UnmarshalBinary(body []byte) error {
-	return fmt.Errorf("Command %s is not supported yet", p.CommandID())
-}
-
 // CancelSm Not supported yet.
 type CancelSm struct{}
 
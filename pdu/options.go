@@ -19,6 +19,15 @@ func NewOptions() *Options {
 	}
 }
 
+// Clone returns a copy of the options that can be changed independently.
+func (o *Options) Clone() *Options {
+	c := NewOptions()
+	for tag, val := range o.fields {
+		c.fields[tag] = append([]byte(nil), val...)
+	}
+	return c
+}
+
 // Set assigns new TLV field.
 func (o *Options) Set(tag TagID, val []byte) *Options {
 	o.fields[tag] = val
@@ -166,6 +175,16 @@ func (o *Options) ReceiptedMessageID() string {
 	return val
 }
 
+// NetworkErrorCode is helper function for getting this option. It returns
+// the network type (1 ANSI-136, 2 IS-95, 3 GSM, ...) and the error code.
+func (o *Options) NetworkErrorCode() (networkType, code int) {
+	val, ok := o.Get(TagNetworkErrorCode)
+	if !ok || len(val) != 3 {
+		return 0, 0
+	}
+	return int(val[0]), int(binary.BigEndian.Uint16(val[1:]))
+}
+
 // SetUserMessageReference is helper function for setting this option.
 func (o *Options) SetUserMessageReference(val int) *Options {
 	return o.SetDouble(TagUserMessageReference, val)
@@ -206,6 +225,11 @@ func (o *Options) SetReceiptedMessageID(val string) *Options {
 	return o.SetCString(TagReceiptedMessageID, val)
 }
 
+// SetNetworkErrorCode is helper function for setting this option.
+func (o *Options) SetNetworkErrorCode(networkType, code int) *Options {
+	return o.Set(TagNetworkErrorCode, []byte{byte(networkType), byte(code >> 8), byte(code)})
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (o *Options) MarshalBinary() ([]byte, error) {
 	var out []byte
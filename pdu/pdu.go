@@ -112,6 +112,8 @@ const (
 	YesDeliveryReceipt = 0x1
 	// receipt requested when final outcome is delivery failure
 	FailDeliveryReceipt = 0x2
+	// receipt requested when final outcome is delivery success (SMPP 5.0)
+	SuccessDeliveryReceipt = 0x3
 )
 
 const (
@@ -126,6 +128,15 @@ const (
 	YesInterNotification = 0x1
 )
 
+// writeTimeValue encodes t as a C-Octet String.
+func writeTimeValue(t smpptime.Time) ([]byte, error) {
+	out, err := t.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return append(out, 0), nil
+}
+
 func writeTime(layout smpptime.Layout, t time.Time) ([]byte, error) {
 	var schedDel []byte
 	if !t.IsZero() {
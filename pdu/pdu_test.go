@@ -3,6 +3,7 @@ package pdu
 import (
 	"bytes"
 	"encoding/hex"
+	"io"
 	"net"
 	"reflect"
 	"strings"
@@ -97,6 +98,18 @@ var pduTT = []struct {
 		},
 		false,
 	},
+	{
+		"minimal query_sm pdu",
+		"3100|00|00|00",
+		&QuerySm{MessageID: "1"},
+		false,
+	},
+	{
+		"minimal query_sm_resp pdu",
+		"00|00|00|00",
+		&QuerySmResp{},
+		false,
+	},
 	// Always append new cases to avoid messing up Encoding/Decoding tests which
 	// rely on indexes in this table.
 }
@@ -186,7 +199,7 @@ func TestSeparateUDH(t *testing.T) {
 var codingTT = []struct {
 	desc      string
 	headerHex string
-	sequencer Sequencer
+	sequencer func() Sequencer // Called per run, nil for the default.
 	pduIndex  int
 	status    Status
 	seq       uint32
@@ -204,7 +217,7 @@ var codingTT = []struct {
 	{
 		"submit_sm with custom sequencer",
 		"0000002D|00000004|00000000|00000003",
-		NewSequencer(3),
+		func() Sequencer { return NewSequencer(3) },
 		0,
 		StatusOK,
 		3,
@@ -251,14 +264,15 @@ var codingTT = []struct {
 func TestPDUEncoding(t *testing.T) {
 	for _, row := range codingTT {
 		t.Run(row.desc, func(t *testing.T) {
-			buf := bytes.NewBuffer(nil)
-			enc := NewEncoder(buf, row.sequencer)
-
+			var seq Sequencer
 			opts := []EncoderOption{EncodeStatus(row.status)}
-			if row.sequencer == nil {
+			if row.sequencer != nil {
+				seq = row.sequencer()
+			} else {
 				opts = append(opts, EncodeSeq(row.seq))
 			}
-			i, err := enc.Encode(pduTT[row.pduIndex].pdu, opts...)
+			enc := NewEncoder(seq)
+			i, got, err := enc.Encode(pduTT[row.pduIndex].pdu, opts...)
 			if err != nil {
 				if !row.err {
 					t.Fatalf("unexpected error %s", err)
@@ -269,7 +283,6 @@ func TestPDUEncoding(t *testing.T) {
 				t.Errorf("Encode() => seq %d expected %d", i, row.seq)
 			}
 			expected, _ := hex.DecodeString(toHexStr(row.headerHex + pduTT[row.pduIndex].hexStr))
-			got := buf.Bytes()
 			if !bytes.Equal(expected, got) {
 				t.Errorf("Encode() => bytes\n%X\nexpected \n%X", got, expected)
 			}
@@ -282,8 +295,7 @@ func TestPDUDecoding(t *testing.T) {
 		t.Run(row.desc, func(t *testing.T) {
 			expected, _ := hex.DecodeString(toHexStr(row.headerHex + pduTT[row.pduIndex].hexStr))
 			buf := bytes.NewBuffer(expected)
-			dec := NewDecoder(buf)
-			h, p, err := dec.Decode()
+			h, p, err := decode(NewDecoder(), buf)
 			if err != nil {
 				if !row.err {
 					t.Fatalf("unexpected error %s", err)
@@ -313,7 +325,7 @@ func TestPDUDecodingIncompleteBuffers(t *testing.T) {
 	}
 
 	buf, wr := net.Pipe()
-	dec := NewDecoder(buf)
+	dec := NewDecoder()
 
 	go func() {
 		for i := 0; i < len(pdus); {
@@ -335,7 +347,7 @@ func TestPDUDecodingIncompleteBuffers(t *testing.T) {
 
 	for _, row := range codingTT {
 		t.Run(row.desc, func(t *testing.T) {
-			h, p, err := dec.Decode()
+			h, p, err := decode(dec, buf)
 			if err != nil {
 				if !row.err {
 					t.Fatalf("unexpected error %s", err)
@@ -355,3 +367,21 @@ func TestPDUDecodingIncompleteBuffers(t *testing.T) {
 		})
 	}
 }
+
+// decode reads a PDU from r the way sessions do: the header first, then
+// the body it announces.
+func decode(dec *Decoder, r io.Reader) (Header, PDU, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, nil, err
+	}
+	h, p, err := dec.DecodeHeader(hdr[:])
+	if err != nil {
+		return h, p, err
+	}
+	body := make([]byte, h.Length()-16)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return h, p, err
+	}
+	return h, p, p.UnmarshalBinary(body)
+}
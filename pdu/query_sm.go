@@ -42,7 +42,7 @@ func (p QuerySm) MarshalBinary() ([]byte, error) {
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p *QuerySm) UnmarshalBinary(body []byte) error {
-	if len(body) < 6 {
+	if len(body) < 4 {
 		return fmt.Errorf("smpp/pdu: query_sm body too short: %d", len(body))
 	}
 	buf := newBuffer(body)
@@ -112,8 +112,8 @@ func (p QuerySmResp) MarshalBinary() ([]byte, error) {
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p *QuerySmResp) UnmarshalBinary(body []byte) error {
-	if len(body) < 6 {
-		return fmt.Errorf("smpp/pdu: query_sm body too short: %d", len(body))
+	if len(body) < 4 {
+		return fmt.Errorf("smpp/pdu: query_sm_resp body too short: %d", len(body))
 	}
 	buf := newBuffer(body)
 	res, err := buf.ReadCString(65)
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -29,6 +28,9 @@ type DeliveryReceipt struct {
 	Stat       DeliveryStat // The final status of the message. See Message states below. State text may be abbreviated.
 	Err        DeliveryErr  // A network or SMSC error code for the message. See Error codes below.
 	Text       string       // Unused field, result will be blank.
+
+	ErrCode string            // Error code as received when it is not a decimal number, e.g. "0A1"; Err is then 0.
+	Extra   map[string]string // Fields outside the standard set, keyed by lowercase name.
 }
 
 const (
@@ -61,9 +63,13 @@ var DelStatMap = map[uint8]DeliveryStat{
 }
 
 func (dr *DeliveryReceipt) String() string {
+	errCode := dr.ErrCode
+	if errCode == "" {
+		errCode = strconv.Itoa(int(dr.Err))
+	}
 	return fmt.Sprintf(
-		"id:%s sub:%d dlvrd:%d submit date:%s done date:%s stat:%s err:%d text:%s",
-		dr.Id, dr.Sub, dr.Dlvrd, dr.SubmitDate.Format(recDateLayout), dr.DoneDate.Format(recDateLayout), dr.Stat, dr.Err, dr.Text,
+		"id:%s sub:%d dlvrd:%d submit date:%s done date:%s stat:%s err:%s text:%s",
+		dr.Id, dr.Sub, dr.Dlvrd, dr.SubmitDate.Format(recDateLayout), dr.DoneDate.Format(recDateLayout), dr.Stat, errCode, dr.Text,
 	)
 }
 
@@ -72,68 +78,137 @@ var (
 	secRecDateLayout = "060102150405"
 )
 
-// ParseDeliveryReceipt parses delivery receipt format defined in smpp 3.4 specification
+// ParseDeliveryReceipt parses a receipt carried in short_message with
+// DefaultReceiptParser.
 func ParseDeliveryReceipt(sm string) (*DeliveryReceipt, error) {
-	var receipt DeliveryReceipt
-	textI := strings.Index(sm, " text:")
-	if textI == -1 {
-		return &DeliveryReceipt{}, errors.New("smpp: invalid receipt txt ")
-	}
-	textMsg := sm[textI+6:]
-	receipt.Text = textMsg
-	formatSm := sm[:textI]
-	formatSm = strings.Replace(formatSm, "done date:", "done_date:", 1)
-	formatSm = strings.Replace(formatSm, "submit date:", "submit_date:", 1)
-	receiptField := strings.Split(formatSm, " ")
-	if len(receiptField) < 7 {
-		return &DeliveryReceipt{}, errors.New("smpp: receipt miss key ")
-	}
-	for _, fieldWithValue := range receiptField {
-		i := strings.Index(fieldWithValue, ":")
-		if i == -1 {
-			return &DeliveryReceipt{}, errors.New("smpp: invalid receipt format field " + fieldWithValue)
-		}
-		switch fieldWithValue[:i] {
-		case "id":
-			receipt.Id = fieldWithValue[i+1:]
-		case "sub", "dlvrd":
-			count, err := strconv.Atoi(fieldWithValue[i+1:])
-			if err != nil {
-				return &DeliveryReceipt{}, err
-			}
-			if fieldWithValue[:i] == "sub" {
-				receipt.Sub = count
-			} else {
-				receipt.Dlvrd = count
-			}
+	return DefaultReceiptParser.Parse(sm)
+}
 
-		case "submit_date", "done_date":
+// MessageState returns the message_state value of stat, or 0 if stat has
+// none.
+func (stat DeliveryStat) MessageState() int {
+	for state, s := range DelStatMap {
+		if s == stat {
+			return int(state)
+		}
+	}
+	return 0
+}
 
-			date, err := time.Parse(recDateLayout, fieldWithValue[i+1:])
-			if err != nil {
-				date, err = time.Parse(secRecDateLayout, fieldWithValue[i+1:])
-				if err != nil {
-					return &DeliveryReceipt{}, err
-				}
+// Final reports whether stat is a final message state.
+func (stat DeliveryStat) Final() bool {
+	return stat != DelStatEnRoute
+}
 
+// NewReceiptDeliverSm creates the deliver_sm reporting receipt for the
+// message orig was submitted as, which the SMSC gave msgID. It returns nil
+// when orig.RegisteredDelivery did not ask for this receipt: final states
+// are reported as requested by its Receipt field, ENROUTE as an
+// intermediate notification when InterNotification is set.
+//
+// The receipt goes from orig's destination back to its source, with its
+// text in short_message and the receipted_message_id, message_state and,
+// when receipt.Err is set, GSM network_error_code TLVs.
+func NewReceiptDeliverSm(orig *SubmitSm, msgID string, receipt DeliveryReceipt) *DeliverSm {
+	esmType := DelRecEsmType
+	rd := orig.RegisteredDelivery
+	if receipt.Stat.Final() {
+		switch rd.Receipt {
+		case YesDeliveryReceipt:
+		case FailDeliveryReceipt:
+			if receipt.Stat == DelStatDelivered {
+				return nil
 			}
-			if fieldWithValue[:i] == "submit_date" {
-				receipt.SubmitDate = date
-			} else {
-				receipt.DoneDate = date
-			}
-		case "stat":
-			receipt.Stat = DeliveryStat(fieldWithValue[i+1:])
-		case "err":
-			count, err := strconv.Atoi(fieldWithValue[i+1:])
-			if err != nil {
-				return &DeliveryReceipt{}, err
+		case SuccessDeliveryReceipt:
+			if receipt.Stat != DelStatDelivered {
+				return nil
 			}
-			receipt.Err = DeliveryErr(count)
 		default:
-			return &DeliveryReceipt{}, errors.New("smpp: invalid receipt format field " + fieldWithValue)
+			return nil
+		}
+	} else {
+		if rd.InterNotification != YesInterNotification {
+			return nil
+		}
+		esmType = IDNEsmType
+	}
+
+	if receipt.Id == "" {
+		receipt.Id = msgID
+	}
+	opts := NewOptions().SetReceiptedMessageID(msgID)
+	if state := receipt.Stat.MessageState(); state != 0 {
+		opts.SetMessageState(state)
+	}
+	if receipt.Err != 0 {
+		opts.SetNetworkErrorCode(3, int(receipt.Err))
+	}
+	if orig.Options != nil {
+		if ref, ok := orig.Options.GetDouble(TagUserMessageReference); ok {
+			opts.SetUserMessageReference(ref)
 		}
+	}
+	return &DeliverSm{
+		ServiceType:     orig.ServiceType,
+		SourceAddrTon:   orig.DestAddrTon,
+		SourceAddrNpi:   orig.DestAddrNpi,
+		SourceAddr:      orig.DestinationAddr,
+		DestAddrTon:     orig.SourceAddrTon,
+		DestAddrNpi:     orig.SourceAddrNpi,
+		DestinationAddr: orig.SourceAddr,
+		EsmClass:        EsmClass{Type: esmType},
+		ShortMessage:    receipt.String(),
+		Options:         opts,
+	}
+}
+
+// ErrNotReceipt is returned by Receipt when esm_class marks the PDU as
+// neither a delivery receipt nor an intermediate notification.
+var ErrNotReceipt = errors.New("smpp: pdu is not a delivery receipt")
 
+// Receipt returns the delivery receipt p carries. The receipt text in
+// short_message, or message_payload when short_message is empty, is merged
+// with the receipted_message_id, message_state and network_error_code
+// TLVs, which take precedence. Either source alone is enough.
+func (p *DeliverSm) Receipt() (*DeliveryReceipt, error) {
+	text := p.ShortMessage
+	if text == "" && p.Options != nil {
+		text = p.Options.MessagePayload()
+	}
+	return mergeReceipt(p.EsmClass, text, p.Options)
+}
+
+// Receipt returns the delivery receipt p carries, merging the receipt text
+// in message_payload with the receipt TLVs like (*DeliverSm).Receipt.
+func (p *DataSm) Receipt() (*DeliveryReceipt, error) {
+	var text string
+	if p.Options != nil {
+		text = p.Options.MessagePayload()
+	}
+	return mergeReceipt(p.EsmClass, text, p.Options)
+}
+
+func mergeReceipt(esm EsmClass, text string, opts *Options) (*DeliveryReceipt, error) {
+	if esm.Type != DelRecEsmType && esm.Type != IDNEsmType {
+		return nil, ErrNotReceipt
+	}
+	dr, err := ParseDeliveryReceipt(text)
+	if opts == nil {
+		return dr, err
+	}
+	// The receipted_message_id TLV is enough to identify the receipt, the
+	// fields read from a malformed text are kept.
+	if id := opts.ReceiptedMessageID(); id != "" {
+		dr.Id = id
+	} else if err != nil {
+		return dr, err
+	}
+	if stat, ok := DelStatMap[uint8(opts.MessageState())]; ok {
+		dr.Stat = stat
+	}
+	if _, ok := opts.Get(TagNetworkErrorCode); ok {
+		_, code := opts.NetworkErrorCode()
+		dr.Err, dr.ErrCode = DeliveryErr(code), ""
 	}
-	return &receipt, nil
+	return dr, nil
 }
@@ -0,0 +1,285 @@
+package pdu
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standard receipt keys, in the order SMPP 3.4 Appendix B lists them.
+const (
+	recKeyID         = "id"
+	recKeySub        = "sub"
+	recKeyDlvrd      = "dlvrd"
+	recKeySubmitDate = "submit date"
+	recKeyDoneDate   = "done date"
+	recKeyStat       = "stat"
+	recKeyErr        = "err"
+	recKeyText       = "text"
+)
+
+var recKeys = []string{recKeyID, recKeySub, recKeyDlvrd, recKeySubmitDate, recKeyDoneDate, recKeyStat, recKeyErr}
+
+// recAliases are key spellings seen in the wild, applied in lenient mode.
+var recAliases = map[string]string{
+	"submitdate": recKeySubmitDate,
+	"donedate":   recKeyDoneDate,
+	"status":     recKeyStat,
+	"error":      recKeyErr,
+}
+
+// recStatNames maps spelled-out states to their abbreviations.
+var recStatNames = map[string]DeliveryStat{
+	"ENROUTE":       DelStatEnRoute,
+	"DELIVERED":     DelStatDelivered,
+	"EXPIRED":       DelStatExpired,
+	"DELETED":       DelStatDeleted,
+	"UNDELIVERABLE": DelStatUndeliverable,
+	"ACCEPTED":      DelStatAccepted,
+	"UNKNOWN":       DelStatUnknown,
+	"REJECTED":      DelStatRejected,
+}
+
+// ReceiptProfile describes the receipt dialect of an SMSC.
+type ReceiptProfile struct {
+	// Aliases maps the SMSC's key names, lowercased, to standard ones,
+	// e.g. "sent" to "submit date".
+	Aliases map[string]string
+	// DateLayouts are tried in order on submit date and done date. They
+	// default to YYMMDDhhmm and, unless strict, YYMMDDhhmmss,
+	// YYYYMMDDhhmm and YYYYMMDDhhmmss.
+	DateLayouts []string
+	// Location the dates are in. Defaults to UTC.
+	Location *time.Location
+	// HexID means the SMSC sends the id in hex while submit_sm_resp
+	// carried it in decimal; the id is converted to decimal.
+	HexID bool
+}
+
+// HexIDReceiptProfile is for SMSCs that send receipt ids in hex.
+var HexIDReceiptProfile = ReceiptProfile{HexID: true}
+
+// ReceiptParser parses delivery receipts carried in short_message.
+//
+// By default it is lenient: keys are matched case-insensitively, with
+// underscores read as spaces, and in any order; only id is required;
+// text may be missing; unknown fields end up in Extra; err may hold
+// letters, in which case it is kept in ErrCode; and stat may be spelled
+// out or lowercase. Values of known fields that cannot be read are
+// still errors.
+//
+// Strict mode checks conformance with the SMPP 3.4 Appendix B layout
+// instead: every standard key in order and in lowercase, text included,
+// no unknown keys, numeric sub, dlvrd and err, and a known stat.
+type ReceiptParser struct {
+	Profile ReceiptProfile
+	Strict  bool
+}
+
+// DefaultReceiptParser is the lenient parser with the default profile.
+var DefaultReceiptParser = ReceiptParser{}
+
+// Parse parses sm. In lenient mode the receipt returned with an error
+// holds every field that could be read.
+func (p ReceiptParser) Parse(sm string) (*DeliveryReceipt, error) {
+	var dr DeliveryReceipt
+	head, text, ok := p.splitText(sm)
+	if ok {
+		dr.Text = text
+	} else if p.Strict {
+		return &DeliveryReceipt{}, errors.New("smpp: receipt has no text field")
+	}
+
+	next := 0      // index in recKeys of the key strict mode expects
+	var ferr error // first field lenient mode couldn't read
+	var prefix []string
+	for _, tok := range strings.Fields(head) {
+		i := strings.IndexByte(tok, ':')
+		if i == -1 {
+			// Part of a key with spaces, like "submit date".
+			prefix = append(prefix, tok)
+			continue
+		}
+		key := strings.Join(append(prefix, tok[:i]), " ")
+		value := tok[i+1:]
+		prefix = prefix[:0]
+
+		key = p.normalizeKey(key)
+		if p.Strict {
+			if next == len(recKeys) || key != recKeys[next] {
+				return &DeliveryReceipt{}, errors.New("smpp: invalid receipt format field " + tok)
+			}
+			next++
+		}
+		if err := p.setField(&dr, key, value); err != nil {
+			if p.Strict {
+				return &DeliveryReceipt{}, err
+			}
+			if ferr == nil {
+				ferr = err
+			}
+		}
+	}
+	if len(prefix) > 0 && p.Strict {
+		return &DeliveryReceipt{}, errors.New("smpp: invalid receipt format field " + strings.Join(prefix, " "))
+	}
+	if p.Strict && next < len(recKeys) {
+		return &DeliveryReceipt{}, errors.New("smpp: receipt miss key " + recKeys[next])
+	}
+	if dr.Id == "" {
+		return &dr, errors.New("smpp: receipt has no id")
+	}
+	return &dr, ferr
+}
+
+// splitText separates the text field, which runs to the end of sm and may
+// contain spaces and colons, from the fields before it.
+func (p ReceiptParser) splitText(sm string) (head, text string, ok bool) {
+	if p.Strict {
+		if strings.HasPrefix(sm, "text:") {
+			return "", sm[5:], true
+		}
+		if i := strings.Index(sm, " text:"); i != -1 {
+			return sm[:i], sm[i+6:], true
+		}
+		return sm, "", false
+	}
+	lower := strings.ToLower(sm)
+	for from := 0; ; {
+		i := strings.Index(lower[from:], "text:")
+		if i == -1 {
+			return sm, "", false
+		}
+		i += from
+		if i == 0 || lower[i-1] == ' ' {
+			return sm[:i], sm[i+5:], true
+		}
+		from = i + 5
+	}
+}
+
+func (p ReceiptParser) normalizeKey(key string) string {
+	if !p.Strict {
+		key = strings.ReplaceAll(strings.ToLower(key), "_", " ")
+	}
+	if k, ok := p.Profile.Aliases[key]; ok {
+		return k
+	}
+	if !p.Strict {
+		if k, ok := recAliases[key]; ok {
+			return k
+		}
+	}
+	return key
+}
+
+func (p ReceiptParser) setField(dr *DeliveryReceipt, key, value string) error {
+	if value == "" && !p.Strict && key != recKeyID {
+		return nil
+	}
+	switch key {
+	case recKeyID:
+		if p.Profile.HexID {
+			n, err := strconv.ParseUint(value, 16, 64)
+			if err != nil {
+				return fmt.Errorf("smpp: receipt id %q is not hex", value)
+			}
+			value = strconv.FormatUint(n, 10)
+		}
+		dr.Id = value
+	case recKeySub, recKeyDlvrd:
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if key == recKeySub {
+			dr.Sub = count
+		} else {
+			dr.Dlvrd = count
+		}
+	case recKeySubmitDate, recKeyDoneDate:
+		date, err := p.parseDate(value)
+		if err != nil {
+			return err
+		}
+		if key == recKeySubmitDate {
+			dr.SubmitDate = date
+		} else {
+			dr.DoneDate = date
+		}
+	case recKeyStat:
+		stat, err := p.parseStat(value)
+		if err != nil {
+			return err
+		}
+		dr.Stat = stat
+	case recKeyErr:
+		code, err := strconv.Atoi(value)
+		if err == nil {
+			dr.Err = DeliveryErr(code)
+			return nil
+		}
+		if p.Strict {
+			return err
+		}
+		if !isAlphanumeric(value) {
+			return fmt.Errorf("smpp: invalid receipt err %q", value)
+		}
+		dr.ErrCode = strings.ToUpper(value)
+	default:
+		if dr.Extra == nil {
+			dr.Extra = make(map[string]string)
+		}
+		dr.Extra[key] = value
+	}
+	return nil
+}
+
+func (p ReceiptParser) parseDate(value string) (time.Time, error) {
+	layouts := p.Profile.DateLayouts
+	if len(layouts) == 0 {
+		layouts = []string{recDateLayout}
+		if !p.Strict {
+			layouts = append(layouts, secRecDateLayout, "200601021504", "20060102150405")
+		}
+	}
+	loc := p.Profile.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	var err error
+	for _, layout := range layouts {
+		var date time.Time
+		if date, err = time.ParseInLocation(layout, value, loc); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func (p ReceiptParser) parseStat(value string) (DeliveryStat, error) {
+	if !p.Strict {
+		value = strings.ToUpper(value)
+		if stat, ok := recStatNames[value]; ok {
+			return stat, nil
+		}
+		return DeliveryStat(value), nil
+	}
+	for _, stat := range DelStatMap {
+		if DeliveryStat(value) == stat {
+			return stat, nil
+		}
+	}
+	return "", fmt.Errorf("smpp: unknown receipt stat %q", value)
+}
+
+func isAlphanumeric(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -30,13 +30,14 @@ func TestParsingGoodDeliveryReceipt(t *testing.T) {
 }
 
 func TestParsingBadDeliveryReceipt(t *testing.T) {
+	strict := ReceiptParser{Strict: true}
 	keys := "id:123123123 dfdfsub:0 dlvrd:0 submit date:1507011202 done date:1507011101 stat:DELIVRD err:0 text:Test information"
-	_, err := ParseDeliveryReceipt(keys)
+	_, err := strict.Parse(keys)
 	if err == nil {
 		t.Errorf("Parsing bad receipt with wrong key name returned no error")
 	}
 	missingkeys := "id:123123123 sub:0 dlvrd:0 submit date:1507011202 stat:DELIVRD err:0 text:Test information"
-	_, err = ParseDeliveryReceipt(missingkeys)
+	_, err = strict.Parse(missingkeys)
 	if err == nil {
 		t.Errorf("Parsing bad receipt with missing keys returned no error")
 	}
@@ -61,6 +62,203 @@ func TestParsingUUIDDeliveryReceipt(t *testing.T) {
 	}
 }
 
+func TestParsingVendorDeliveryReceipts(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		parser  ReceiptParser
+		sm      string
+		id      string
+		stat    DeliveryStat
+		err     DeliveryErr
+		errCode string
+		done    string
+		text    string
+		extra   map[string]string
+	}{
+		{
+			name: "uppercase keys",
+			sm:   "ID:42 SUB:001 DLVRD:001 SUBMIT DATE:1507011202 DONE DATE:1507011203 STAT:delivrd ERR:000 TEXT:Hi",
+			id:   "42", stat: DelStatDelivered, done: "2015-07-01T12:03:00Z", text: "Hi",
+		},
+		{
+			name: "missing text and seconds",
+			sm:   "id:42 sub:1 dlvrd:0 submit date:150701120230 done date:150701120345 stat:UNDELIV err:011",
+			id:   "42", stat: DelStatUndeliverable, err: 11, done: "2015-07-01T12:03:45Z",
+		},
+		{
+			name: "extra fields and alphanumeric err",
+			sm:   "id:42 nack:0 imsi:432110123456789 submit_date:1507011202 done_date:1507011203 stat:REJECTD err:0A1 text:",
+			id:   "42", stat: DelStatRejected, errCode: "0A1", done: "2015-07-01T12:03:00Z",
+			extra: map[string]string{"nack": "0", "imsi": "432110123456789"},
+		},
+		{
+			name:   "hex id",
+			parser: ReceiptParser{Profile: HexIDReceiptProfile},
+			sm:     "id:2A sub:1 dlvrd:1 submit date:1507011202 done date:1507011203 stat:DELIVERED err:0 text:a text: b",
+			id:     "42", stat: DelStatDelivered, done: "2015-07-01T12:03:00Z", text: "a text: b",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dr, err := tt.parser.Parse(tt.sm)
+			if err != nil {
+				t.Fatalf("Parse() error %v", err)
+			}
+			if dr.Id != tt.id || dr.Stat != tt.stat || dr.Err != tt.err || dr.ErrCode != tt.errCode || dr.Text != tt.text {
+				t.Errorf("Parse() => %+v", dr)
+			}
+			if got := dr.DoneDate.Format(time.RFC3339); got != tt.done {
+				t.Errorf("Parse() done date %s expected %s", got, tt.done)
+			}
+			if len(dr.Extra) != len(tt.extra) {
+				t.Errorf("Parse() extra %v expected %v", dr.Extra, tt.extra)
+			}
+			for k, v := range tt.extra {
+				if dr.Extra[k] != v {
+					t.Errorf("Parse() extra %s=%q expected %q", k, dr.Extra[k], v)
+				}
+			}
+			if _, err := (ReceiptParser{Profile: tt.parser.Profile, Strict: true}).Parse(tt.sm); err == nil {
+				t.Errorf("strict Parse() accepted %q", tt.sm)
+			}
+		})
+	}
+}
+
+func TestParsingStrictDeliveryReceipt(t *testing.T) {
+	strict := ReceiptParser{Strict: true}
+	good := "id:123123123 sub:001 dlvrd:001 submit date:1507011202 done date:1507011101 stat:DELIVRD err:000 text:Test information"
+	if _, err := strict.Parse(good); err != nil {
+		t.Errorf("strict Parse() error %v", err)
+	}
+	for _, bad := range []string{
+		"id:1 dlvrd:001 sub:001 submit date:1507011202 done date:1507011101 stat:DELIVRD err:000 text:",
+		"id:1 sub:001 dlvrd:001 submit date:1507011202 done date:1507011101 stat:DELIVRD err:000",
+		"id:1 sub:001 dlvrd:001 submit date:1507011202 done date:1507011101 stat:GONE err:000 text:",
+		"id:1 sub:001 dlvrd:001 submit date:1507011202 done date:1507011101 stat:DELIVRD err:000 nack:0 text:",
+	} {
+		if _, err := strict.Parse(bad); err == nil {
+			t.Errorf("strict Parse() accepted %q", bad)
+		}
+	}
+	if _, err := ParseDeliveryReceipt("sub:1 stat:DELIVRD"); err == nil {
+		t.Errorf("Parsing receipt without id returned no error")
+	}
+}
+
+func TestDeliverSmReceipt(t *testing.T) {
+	text := "id:1F sub:001 dlvrd:000 submit date:1507011202 done date:1507011203 stat:ENROUTE err:000 text:"
+	p := &DeliverSm{
+		EsmClass:     EsmClass{Type: DelRecEsmType},
+		ShortMessage: text,
+		Options: NewOptions().
+			SetReceiptedMessageID("31").
+			SetMessageState(5).
+			SetNetworkErrorCode(3, 34),
+	}
+	dr, err := p.Receipt()
+	if err != nil {
+		t.Fatalf("Receipt() error %v", err)
+	}
+	if dr.Id != "31" || dr.Stat != DelStatUndeliverable || dr.Err != 34 || dr.Sub != 1 {
+		t.Errorf("Receipt() => %+v", dr)
+	}
+
+	p.ShortMessage = "id:1F sub:001 dlvrd:000 submit date:15070xx done date:1507011203 stat:DELIVRD err:000 text:hi"
+	p.Options = NewOptions().SetReceiptedMessageID("31")
+	dr, err = p.Receipt()
+	if err != nil {
+		t.Fatalf("Receipt() with malformed text error %v", err)
+	}
+	done := time.Date(2015, 7, 1, 12, 3, 0, 0, time.UTC)
+	if dr.Id != "31" || dr.Sub != 1 || dr.Stat != DelStatDelivered || dr.Text != "hi" || !dr.DoneDate.Equal(done) {
+		t.Errorf("Receipt() with malformed text => %+v", dr)
+	}
+
+	p.Options.SetMessageState(5)
+	p.ShortMessage = ""
+	if dr, err = p.Receipt(); err != nil || dr.Id != "31" || dr.Stat != DelStatUndeliverable {
+		t.Errorf("Receipt() from TLVs only => %+v, %v", dr, err)
+	}
+
+	p.Options = nil
+	if _, err = p.Receipt(); err == nil {
+		t.Errorf("Receipt() without text or TLVs returned no error")
+	}
+
+	p.EsmClass.Type = DefaultEsmType
+	if _, err = p.Receipt(); err != ErrNotReceipt {
+		t.Errorf("Receipt() on a plain message => %v, expected ErrNotReceipt", err)
+	}
+}
+
+func TestDataSmReceipt(t *testing.T) {
+	p := &DataSm{
+		EsmClass: EsmClass{Type: IDNEsmType},
+		Options: NewOptions().
+			SetMessagePayload("id:42 stat:ENROUTE err:0A1").
+			SetMessageState(1),
+	}
+	dr, err := p.Receipt()
+	if err != nil {
+		t.Fatalf("Receipt() error %v", err)
+	}
+	if dr.Id != "42" || dr.Stat != DelStatEnRoute || dr.ErrCode != "0A1" {
+		t.Errorf("Receipt() => %+v", dr)
+	}
+}
+
+func TestNewReceiptDeliverSm(t *testing.T) {
+	orig := &SubmitSm{
+		SourceAddrTon:   5,
+		SourceAddr:      "Bank",
+		DestAddrTon:     1,
+		DestAddrNpi:     1,
+		DestinationAddr: "989120000000",
+		Options:         NewOptions().SetUserMessageReference(9),
+	}
+	for _, tt := range []struct {
+		registered RegisteredDelivery
+		stat       DeliveryStat
+		esmType    int
+	}{
+		{RegisteredDelivery{Receipt: NoDeliveryReceipt}, DelStatDelivered, -1},
+		{RegisteredDelivery{Receipt: YesDeliveryReceipt}, DelStatDelivered, DelRecEsmType},
+		{RegisteredDelivery{Receipt: YesDeliveryReceipt}, DelStatExpired, DelRecEsmType},
+		{RegisteredDelivery{Receipt: FailDeliveryReceipt}, DelStatDelivered, -1},
+		{RegisteredDelivery{Receipt: FailDeliveryReceipt}, DelStatUndeliverable, DelRecEsmType},
+		{RegisteredDelivery{Receipt: SuccessDeliveryReceipt}, DelStatDelivered, DelRecEsmType},
+		{RegisteredDelivery{Receipt: SuccessDeliveryReceipt}, DelStatRejected, -1},
+		{RegisteredDelivery{Receipt: YesDeliveryReceipt}, DelStatEnRoute, -1},
+		{RegisteredDelivery{InterNotification: YesInterNotification}, DelStatEnRoute, IDNEsmType},
+	} {
+		orig.RegisteredDelivery = tt.registered
+		p := NewReceiptDeliverSm(orig, "77", DeliveryReceipt{Stat: tt.stat, Err: 1})
+		if tt.esmType == -1 {
+			if p != nil {
+				t.Errorf("%+v %s: unexpected receipt", tt.registered, tt.stat)
+			}
+			continue
+		}
+		if p == nil {
+			t.Fatalf("%+v %s: no receipt", tt.registered, tt.stat)
+		}
+		if p.EsmClass.Type != tt.esmType || p.SourceAddr != orig.DestinationAddr || p.DestinationAddr != orig.SourceAddr ||
+			p.SourceAddrTon != 1 || p.DestAddrTon != 5 {
+			t.Errorf("%+v %s: receipt %+v", tt.registered, tt.stat, p)
+		}
+		if p.Options.UserMessageReference() != 9 {
+			t.Errorf("user_message_reference not copied")
+		}
+		dr, err := p.Receipt()
+		if err != nil {
+			t.Fatalf("Receipt() error %v", err)
+		}
+		if dr.Id != "77" || dr.Stat != tt.stat || dr.Err != 1 {
+			t.Errorf("Receipt() => %+v", dr)
+		}
+	}
+}
+
 func BenchmarkParseDeliveryReceipt(b *testing.B) {
 	good := "id:123123123 sub:0 dlvrd:0 submit date:1507011202 done date:1507011101 stat:DELIVRD err:0 text:Test information"
 	b.ResetTimer()
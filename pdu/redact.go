@@ -0,0 +1,107 @@
+package pdu
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Redacted is printed by String methods in place of passwords and message
+// content: the password of binds, short_message and the message_payload
+// TLV. Use Unredacted to print them.
+const Redacted = "<redacted>"
+
+// Unredacted returns p printed with its passwords and message content.
+func Unredacted(p PDU) fmt.Stringer {
+	return unredacted{p}
+}
+
+type unredacted struct {
+	p PDU
+}
+
+func (u unredacted) String() string {
+	return dump(u.p, false)
+}
+
+// sensitiveFields are the PDU fields String hides.
+var sensitiveFields = map[string]bool{
+	"Password":     true,
+	"ShortMessage": true,
+}
+
+// sensitiveTags are the TLVs String hides.
+var sensitiveTags = map[TagID]bool{
+	TagMessagePayload: true,
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// dump prints the fields of the struct v, or of the struct it points to,
+// one per line.
+func dump(v interface{}, redacted bool) string {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return fmt.Sprint(v)
+	}
+	typ := val.Type()
+
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for i := 0; i < val.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		var value interface{} = val.Field(i).Interface()
+		switch f := value.(type) {
+		case string:
+			if redacted && sensitiveFields[field.Name] {
+				value = redact(f)
+			}
+		case *Options:
+			value = f.format(redacted)
+		}
+		sb.WriteString(fmt.Sprintf(" %s: %v\n", field.Name, value))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// String prints the TLVs in tag order with their values in hex, hiding
+// message_payload.
+func (o *Options) String() string {
+	return o.format(true)
+}
+
+func (o *Options) format(redacted bool) string {
+	if o == nil {
+		return "<nil>"
+	}
+	tags := make([]TagID, 0, len(o.fields))
+	for tag := range o.fields {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, tag := range tags {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		if redacted && sensitiveTags[tag] {
+			sb.WriteString(fmt.Sprintf("%s:%s", tag, Redacted))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%s:%x", tag, o.fields[tag]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
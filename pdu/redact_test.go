@@ -0,0 +1,31 @@
+package pdu
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	tests := []struct {
+		p      PDU
+		secret string
+	}{
+		{&BindTx{SystemID: "esme", Password: "s3cr3t"}, "s3cr3t"},
+		{&BindRx{SystemID: "esme", Password: "s3cr3t"}, "s3cr3t"},
+		{&BindTRx{SystemID: "esme", Password: "s3cr3t"}, "s3cr3t"},
+		{&SubmitSm{SourceAddr: "111", ShortMessage: "hello there"}, "hello there"},
+		{&DeliverSm{SourceAddr: "111", ShortMessage: "hello there"}, "hello there"},
+		{&ReplaceSm{MessageID: "m1", ShortMessage: "hello there"}, "hello there"},
+		{&SubmitSm{SourceAddr: "111", Options: NewOptions().SetMessagePayload("hello there")}, "68656c6c6f"},
+	}
+	for _, tt := range tests {
+		s := fmt.Sprint(tt.p)
+		if strings.Contains(s, tt.secret) || !strings.Contains(s, Redacted) {
+			t.Errorf("%s String() => %q", tt.p.CommandID(), s)
+		}
+		if s := Unredacted(tt.p).String(); !strings.Contains(s, tt.secret) {
+			t.Errorf("%s Unredacted() => %q", tt.p.CommandID(), s)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package pdu
+
+import (
+	"testing"
+	"time"
+
+	smpptime "github.com/majiddarvishan/smpp/time"
+)
+
+func TestReplaceSmRoundTrip(t *testing.T) {
+	p := ReplaceSm{
+		MessageID:            "abc",
+		SourceAddrTon:        1,
+		SourceAddrNpi:        1,
+		SourceAddr:           "111",
+		ScheduleDeliveryTime: smpptime.NewAbsolute(time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("", 3*3600+1800))),
+		ValidityPeriod:       smpptime.NewRelative(smpptime.Period{Days: 1, Hours: 2}),
+		RegisteredDelivery:   RegisteredYesDeliveryReceipt(),
+		ShortMessage:         "updated",
+	}
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got ReplaceSm
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got.ScheduleDeliveryTime.String() != "240301100000014+" || got.ValidityPeriod.String() != "000001020000000R" {
+		t.Errorf("times => %s %s", got.ScheduleDeliveryTime, got.ValidityPeriod)
+	}
+	if got.MessageID != p.MessageID || got.SourceAddr != p.SourceAddr || got.ShortMessage != p.ShortMessage ||
+		got.RegisteredDelivery != p.RegisteredDelivery {
+		t.Errorf("UnmarshalBinary() => %+v", got)
+	}
+}
+
+func TestSubmitSmRelativeValidity(t *testing.T) {
+	p := SubmitSm{
+		SourceAddr:      "111",
+		DestinationAddr: "222",
+		ValidityPeriod:  smpptime.NewRelative(smpptime.PeriodOf(90 * time.Minute)),
+	}
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got SubmitSm
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	period, ok := got.ValidityPeriod.Relative()
+	if !ok || period != (smpptime.Period{Hours: 1, Minutes: 30}) {
+		t.Errorf("ValidityPeriod => %s", got.ValidityPeriod)
+	}
+	if !got.ScheduleDeliveryTime.IsZero() {
+		t.Errorf("ScheduleDeliveryTime => %s, expected null", got.ScheduleDeliveryTime)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-	"time"
 
 	smpptime "github.com/majiddarvishan/smpp/time"
 )
@@ -24,8 +23,8 @@ type SubmitSm struct {
 	EsmClass             EsmClass
 	ProtocolID           int
 	PriorityFlag         int
-	ScheduleDeliveryTime time.Time
-	ValidityPeriod       time.Time
+	ScheduleDeliveryTime smpptime.Time
+	ValidityPeriod       smpptime.Time
 	RegisteredDelivery   RegisteredDelivery
 	ReplaceIfPresentFlag int
 	DataCoding           int // DataCoding see more:https://en.wikipedia.org/wiki/Data_Coding_Scheme
@@ -58,12 +57,12 @@ func (p SubmitSm) MarshalBinary() ([]byte, error) {
 	out = append(out, byte(p.DestAddrTon), byte(p.DestAddrNpi))
 	out = append(out, append([]byte(p.DestinationAddr), 0)...)
 	out = append(out, p.EsmClass.Byte(), byte(p.ProtocolID), byte(p.PriorityFlag))
-	tm, err := writeTime(smpptime.Absolute, p.ScheduleDeliveryTime)
+	tm, err := writeTimeValue(p.ScheduleDeliveryTime)
 	if err != nil {
 		return nil, err
 	}
 	out = append(out, tm...)
-	tm, err = writeTime(smpptime.Absolute, p.ValidityPeriod)
+	tm, err = writeTimeValue(p.ValidityPeriod)
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +142,7 @@ func (p *SubmitSm) UnmarshalBinary(body []byte) error {
 	if err != nil {
 		return fmt.Errorf("smpp/pdu: decoding schedule_delivery_time %s", err)
 	}
-	t, err := smpptime.Parse(res)
+	t, err := smpptime.ParseTime(res)
 	if err != nil {
 		return fmt.Errorf("smpp/pdu: decoding schedule_delivery_time %s", err)
 	}
@@ -152,7 +151,7 @@ func (p *SubmitSm) UnmarshalBinary(body []byte) error {
 	if err != nil {
 		return fmt.Errorf("smpp/pdu: decoding validity_period %s", err)
 	}
-	t, err = smpptime.Parse(res)
+	t, err = smpptime.ParseTime(res)
 	if err != nil {
 		return fmt.Errorf("smpp/pdu: decoding validity_period %s", err)
 	}
@@ -194,20 +193,9 @@ func (p *SubmitSm) UnmarshalBinary(body []byte) error {
 	return nil
 }
 
+// String prints the fields of p, hiding short_message and message_payload.
 func (p SubmitSm) String() string {
-    val := reflect.ValueOf(p)
-    typ := reflect.TypeOf(p)
-
-    var sb strings.Builder
-    sb.WriteString("{\n")
-
-    for i := 0; i < val.NumField(); i++ {
-        field := typ.Field(i)
-        value := val.Field(i)
-        sb.WriteString(fmt.Sprintf(" %s: %v\n", field.Name, value))
-    }
-    sb.WriteString("}\n")
-    return sb.String()
+	return dump(p, true)
 }
 
 // SubmitSmResp contains mandatory fields for submit_sm response.
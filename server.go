@@ -2,9 +2,12 @@ package smpp
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"sync"
 	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
 )
 
 // tcpKeepAliveListener sets TCP keep-alive timeouts on accepted
@@ -29,16 +32,29 @@ func (ln tcpKeepAliveListener) Accept() (c net.Conn, err error) {
 	return tc, nil
 }
 
+// ListenerConf describes additional address that server accepts connections on.
+type ListenerConf struct {
+	Addr string
+	// TLSConfig enables TLS on the listener when set.
+	TLSConfig *tls.Config
+}
+
 // Server implements SMPP SMSC server.
 type Server struct {
 	Addr        string
 	SessionConf *SessionConf
+	// Listeners are served by ListenAndServe together with Addr.
+	Listeners []ListenerConf
+	// UnbindTimeout bounds Unbind and Shutdown when their context has no
+	// deadline. Defaults to 10 seconds.
+	UnbindTimeout time.Duration
 
 	wg         sync.WaitGroup
 	mu         sync.Mutex
 	listeners  map[net.Listener]struct{}
 	doneChan   chan struct{}
 	activeSess map[*Session]struct{}
+	inShutdown bool
 }
 
 // NewServer creates new SMPP server for managing SMSC sessions.
@@ -50,18 +66,56 @@ func NewServer(addr string, conf SessionConf) *Server {
 	}
 }
 
-// ListenAndServe starts server listening. Blocking function.
+// ListenAndServe starts server listening on Addr and all configured Listeners.
+// Blocking function, it returns once every listener stops serving.
 func (srv *Server) ListenAndServe() error {
-	addr := srv.Addr
+	confs := srv.Listeners
+	if srv.Addr != "" || len(confs) == 0 {
+		confs = append([]ListenerConf{{Addr: srv.Addr}}, confs...)
+	}
+	lns := make([]net.Listener, 0, len(confs))
+	for _, lc := range confs {
+		ln, err := listen(lc)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return err
+		}
+		lns = append(lns, ln)
+	}
+	if len(lns) == 1 {
+		return srv.Serve(lns[0])
+	}
+	errs := make(chan error, len(lns))
+	for _, ln := range lns {
+		go func(ln net.Listener) {
+			errs <- srv.Serve(ln)
+		}(ln)
+	}
+	var err error
+	for range lns {
+		if serr := <-errs; serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+func listen(lc ListenerConf) (net.Listener, error) {
+	addr := lc.Addr
 	if addr == "" {
 		addr = ":2775"
 	}
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	return srv.Serve(tcpKeepAliveListener{ln.(*net.TCPListener)})
+	var out net.Listener = tcpKeepAliveListener{ln.(*net.TCPListener)}
+	if lc.TLSConfig != nil {
+		out = tls.NewListener(out, lc.TLSConfig)
+	}
+	return out, nil
 }
 
 // Serve accepts incoming connections and starts SMPP sessions.
@@ -78,6 +132,9 @@ func (srv *Server) Serve(ln net.Listener) error {
 				return nil
 			default:
 			}
+			if srv.shuttingDown() {
+				return nil
+			}
 			if ne, ok := err.(net.Error); ok && ne.Temporary() {
 				if tempDelay == 0 {
 					tempDelay = 5 * time.Millisecond
@@ -110,14 +167,101 @@ func (srv *Server) Serve(ln net.Listener) error {
 	}
 }
 
+// UnbindResult is the outcome of unbinding single session during server shutdown.
+type UnbindResult struct {
+	SessionID string
+	SystemID  string
+	// Err is set if unbind couldn't be sent or peer didn't answer it before
+	// the deadline, in which case the session was closed forcefully.
+	Err error
+}
+
 // Unbind gracefully closes server by sending Unbind requests to all connected peers.
-func (srv *Server) Unbind(ctx context.Context) error {
+// New connections are no longer accepted. Sessions are unbound in parallel and
+// each of them is closed forcefully if it doesn't receive unbind_resp and finish
+// in-flight handlers before ctx expires. If ctx has no deadline UnbindTimeout
+// is used. Outcome for every session is returned together with the first
+// encountered error.
+func (srv *Server) Unbind(ctx context.Context) ([]UnbindResult, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := srv.UnbindTimeout
+		if timeout == 0 {
+			timeout = 10 * time.Second
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	srv.mu.Lock()
+	srv.inShutdown = true
+	lnerr := srv.closeListenersLocked()
+	sessions := make([]*Session, 0, len(srv.activeSess))
 	for sess := range srv.activeSess {
-		_ = Unbind(ctx, sess)
+		sessions = append(sessions, sess)
 	}
 	srv.mu.Unlock()
-	return srv.Close()
+
+	results := make([]UnbindResult, len(sessions))
+	var wg sync.WaitGroup
+	for i, sess := range sessions {
+		wg.Add(1)
+		go func(i int, sess *Session) {
+			defer wg.Done()
+			results[i] = unbindSession(ctx, sess)
+		}(i, sess)
+	}
+	wg.Wait()
+
+	err := lnerr
+	for _, res := range results {
+		if res.Err != nil && err == nil {
+			err = res.Err
+		}
+	}
+	if cerr := srv.Close(); err == nil {
+		err = cerr
+	}
+	return results, err
+}
+
+// Shutdown gracefully stops the server. It stops accepting new connections,
+// sends unbind to every bound session and waits for sessions to receive
+// unbind_resp and finish in-flight handlers. Sessions that are still active
+// when ctx expires are closed forcefully and ctx error is returned.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	_, err := srv.Unbind(ctx)
+	return err
+}
+
+func unbindSession(ctx context.Context, sess *Session) UnbindResult {
+	sess.mu.Lock()
+	res := UnbindResult{
+		SessionID: sess.ID(),
+		SystemID:  sess.SystemID(),
+	}
+	sess.mu.Unlock()
+	if !sess.bound() {
+		sess.Close()
+		return res
+	}
+	// Don't let slow peer block sending beyond the deadline.
+	if dl, ok := ctx.Deadline(); ok {
+		if wd, ok := sess.RWC.(interface{ SetWriteDeadline(time.Time) error }); ok {
+			_ = wd.SetWriteDeadline(dl)
+		}
+	}
+	if _, err := sess.SendRequest(ctx, pdu.Unbind{}); err != nil {
+		res.Err = err
+		sess.Close()
+		return res
+	}
+	select {
+	case <-sess.NotifyClosed():
+	case <-ctx.Done():
+		res.Err = ctx.Err()
+		sess.Close()
+	}
+	return res
 }
 
 // Close implements closer interface.
@@ -136,6 +280,12 @@ func (srv *Server) getDoneChan() <-chan struct{} {
 	return srv.getDoneChanLocked()
 }
 
+func (srv *Server) shuttingDown() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.inShutdown
+}
+
 func (srv *Server) getDoneChanLocked() chan struct{} {
 	if srv.doneChan == nil {
 		srv.doneChan = make(chan struct{})
@@ -165,6 +315,7 @@ func (srv *Server) trackListener(ln net.Listener, add bool) {
 		// Close or Shutdown, reset its doneChan:
 		if len(srv.listeners) == 0 && len(srv.activeSess) == 0 {
 			srv.doneChan = nil
+			srv.inShutdown = false
 		}
 		srv.listeners[ln] = struct{}{}
 	} else {
@@ -11,13 +11,23 @@ import (
 	"github.com/majiddarvishan/smpp/pdu"
 )
 
-const (
-	TestAddr = ":30303"
-)
+// serve starts srv on a free local port. It returns the address and the
+// result of Serve.
+func serve(t *testing.T, srv *smpp.Server) (string, <-chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+	return ln.Addr().String(), served
+}
 
 func TestSMPPServer(t *testing.T) {
 	sessConf := smpp.SessionConf{
-		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+		RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
 			switch ctx.CommandID() {
 			case pdu.BindTransceiverID:
 				btrx, err := ctx.BindTRx()
@@ -31,15 +41,15 @@ func TestSMPPServer(t *testing.T) {
 			}
 		}),
 	}
-	srv := smpp.NewServer(TestAddr, sessConf)
-	go func() {
-		err := srv.ListenAndServe()
-		if err != nil {
+	srv := smpp.NewServer("", sessConf)
+	addr, served := serve(t, srv)
+	defer func() {
+		srv.Close()
+		if err := <-served; err != nil {
 			t.Errorf("Expected no error on server close %v", err)
 		}
 	}()
-	time.Sleep(time.Millisecond * 10)
-	sess1 := bindToServer(TestAddr, smpp.HandlerFunc(func(ctx *smpp.Context) {
+	sess1 := bindToServerConf(addr, smpp.SessionConf{RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
 		switch ctx.CommandID() {
 		case pdu.UnbindID:
 			ubd, err := ctx.Unbind()
@@ -51,8 +61,8 @@ func TestSMPPServer(t *testing.T) {
 				t.Errorf(err.Error())
 			}
 		}
-	}))
-	sess2 := bindToServer(TestAddr, smpp.HandlerFunc(func(ctx *smpp.Context) {
+	})})
+	sess2 := bindToServerConf(addr, smpp.SessionConf{RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
 		switch ctx.CommandID() {
 		case pdu.UnbindID:
 			ubd, err := ctx.Unbind()
@@ -64,10 +74,10 @@ func TestSMPPServer(t *testing.T) {
 				t.Errorf(err.Error())
 			}
 		}
-	}))
+	})})
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
-	err := srv.Unbind(ctx)
+	_, err := srv.Unbind(ctx)
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -83,22 +93,6 @@ func TestSMPPServer(t *testing.T) {
 	}
 }
 
-func bindToServer(bind string, hf smpp.HandlerFunc) *smpp.Session {
-	bc := smpp.BindConf{
-		Addr:     bind,
-		SystemID: "Client",
-		Password: "password",
-	}
-	sc := smpp.SessionConf{
-		Handler: hf,
-	}
-	sess, err := smpp.BindTRx(sc, bc)
-	if err != nil {
-		log.Fatalf("error during bind %v", err)
-	}
-	return sess
-}
-
 func TestServerHandlesClientDisconnect(t *testing.T) {
 	stateChangeCh := make(chan smpp.SessionState, 10)
 	sessConf := smpp.SessionConf{
@@ -106,24 +100,20 @@ func TestServerHandlesClientDisconnect(t *testing.T) {
 		SendWinSize:   0,
 		ReqWinSize:    0,
 		WindowTimeout: 0,
-		SessionState: func(id string, systemID string, state smpp.SessionState) {
+		SessionState: func(id string, systemID string, state smpp.SessionState, reason smpp.CloseReason) {
 			stateChangeCh <- state
 		},
 		SystemID:         "",
 		ID:               "",
 		Logger:           smpp.DefaultLogger{},
-		Handler:          smpp.HandlerFunc(func(ctx *smpp.Context) {}),
+		RequestHandler:   smpp.RequestHandlerFunc(func(ctx *smpp.Context) {}),
 		Sequencer:        nil,
 		MapResetInterval: 0,
 	}
-	srv := smpp.NewServer(TestAddr, sessConf)
-	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			t.Fatalf("Expected no error on server close, got %v", err)
-		}
-	}()
+	srv := smpp.NewServer("", sessConf)
+	addr, served := serve(t, srv)
 
-	conn, err := net.Dial("tcp", TestAddr)
+	conn, err := net.Dial("tcp", addr)
 	if err != nil {
 		t.Fatalf("Failed to connect to server: %v", err)
 	}
@@ -138,6 +128,273 @@ func TestServerHandlesClientDisconnect(t *testing.T) {
 		t.Fatalf("Timeout waiting for session state change")
 	}
 	srv.Close()
-	// You might want to shutdown the server here to end the test.
-	// srv.Close() or similar...
+	if err := <-served; err != nil {
+		t.Errorf("Expected no error on server close, got %v", err)
+	}
+}
+
+func TestServerShutdownMultipleListeners(t *testing.T) {
+	sessConf := smpp.SessionConf{
+		RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
+			switch ctx.CommandID() {
+			case pdu.BindTransceiverID:
+				btrx, err := ctx.BindTRx()
+				if err != nil {
+					t.Errorf(err.Error())
+				}
+				if err := ctx.Respond(btrx.Response("TestingServer"), pdu.StatusOK); err != nil {
+					t.Errorf(err.Error())
+				}
+			}
+		}),
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {}),
+	}
+	srv := smpp.NewServer("", sessConf)
+	addr1, served1 := serve(t, srv)
+	addr2, served2 := serve(t, srv)
+	unbindResponder := smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
+		if ctx.CommandID() == pdu.UnbindID {
+			ubd, err := ctx.Unbind()
+			if err != nil {
+				t.Errorf(err.Error())
+			}
+			if err := ctx.Respond(ubd.Response(), pdu.StatusOK); err != nil {
+				t.Errorf(err.Error())
+			}
+		}
+	})
+	sess1 := bindToServerConf(addr1, smpp.SessionConf{
+		RequestHandler:  unbindResponder,
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {}),
+	})
+	sess2 := bindToServerConf(addr2, smpp.SessionConf{
+		RequestHandler:  unbindResponder,
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {}),
+	})
+	if n := len(srv.Stats().Sessions); n != 2 {
+		t.Fatalf("expected 2 bound sessions got %d", n)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Errorf("expected graceful shutdown got %v", err)
+	}
+	if n := len(srv.Stats().Sessions); n != 0 {
+		t.Errorf("expected no sessions after shutdown got %d", n)
+	}
+	for _, served := range []<-chan error{served1, served2} {
+		select {
+		case err := <-served:
+			if err != nil {
+				t.Errorf("expected no error on server shutdown got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Error("server didn't stop serving")
+		}
+	}
+	for _, sess := range []*smpp.Session{sess1, sess2} {
+		select {
+		case <-sess.NotifyClosed():
+		case <-time.After(time.Second):
+			t.Errorf("session %s was not closed in time", sess)
+		}
+	}
+}
+
+func TestServerShutdownForcesUnresponsivePeers(t *testing.T) {
+	sessConf := smpp.SessionConf{
+		RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
+			if ctx.CommandID() == pdu.BindTransceiverID {
+				btrx, _ := ctx.BindTRx()
+				ctx.Respond(btrx.Response("TestingServer"), pdu.StatusOK)
+			}
+		}),
+	}
+	srv := smpp.NewServer("", sessConf)
+	addr, _ := serve(t, srv)
+	// Peer never answers unbind.
+	sess := bindToServerConf(addr, smpp.SessionConf{
+		RequestHandler:  smpp.RequestHandlerFunc(func(ctx *smpp.Context) {}),
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {}),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+	if n := len(srv.Stats().Sessions); n != 0 {
+		t.Errorf("expected forced close of all sessions got %d left", n)
+	}
+	select {
+	case <-sess.NotifyClosed():
+	case <-time.After(time.Second):
+		t.Errorf("session %s was not closed in time", sess)
+	}
+}
+
+// bindToServerConf binds to addr and waits for the bind response, which is
+// not passed to sc.ResponseHandler.
+func bindToServerConf(addr string, sc smpp.SessionConf) *smpp.Session {
+	bc := smpp.BindConf{
+		Addr:     addr,
+		SystemID: "Client",
+		Password: "password",
+	}
+	bound := make(chan pdu.Status, 1)
+	next := sc.ResponseHandler
+	sc.ResponseHandler = smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {
+		if ctx.CommandID() == pdu.BindTransceiverRespID {
+			bound <- ctx.Header().Status()
+			return
+		}
+		if next != nil {
+			next.ServeSMPP(ctx)
+		}
+	})
+	sess, err := smpp.BindTRx(sc, bc)
+	if err != nil {
+		log.Fatalf("error during bind %v", err)
+	}
+	select {
+	case status := <-bound:
+		if status != pdu.StatusOK {
+			log.Fatalf("bind failed %s", status)
+		}
+	case <-time.After(time.Second):
+		log.Fatal("timeout waiting for bind response")
+	}
+	return sess
+}
+
+func TestServerUnbindReportsPerSessionResults(t *testing.T) {
+	sessConf := smpp.SessionConf{
+		RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
+			if ctx.CommandID() == pdu.BindTransceiverID {
+				btrx, _ := ctx.BindTRx()
+				ctx.Respond(btrx.Response("TestingServer"), pdu.StatusOK)
+			}
+		}),
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {}),
+	}
+	srv := smpp.NewServer("", sessConf)
+	addr, _ := serve(t, srv)
+	responsive := bindToServerConf(addr, smpp.SessionConf{
+		RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
+			if ctx.CommandID() == pdu.UnbindID {
+				ubd, _ := ctx.Unbind()
+				ctx.Respond(ubd.Response(), pdu.StatusOK)
+			}
+		}),
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {}),
+	})
+	silent := make([]*smpp.Session, 3)
+	for i := range silent {
+		silent[i] = bindToServerConf(addr, smpp.SessionConf{
+			RequestHandler:  smpp.RequestHandlerFunc(func(ctx *smpp.Context) {}),
+			ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {}),
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results, err := srv.Unbind(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+	// Silent peers are waited on in parallel so the deadline is not multiplied.
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("unbind took too long %s", elapsed)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results got %d", len(results))
+	}
+	failed := 0
+	for _, res := range results {
+		if res.Err != nil {
+			failed++
+		}
+	}
+	if failed != len(silent) {
+		t.Errorf("expected %d failed unbinds got %d", len(silent), failed)
+	}
+	for _, sess := range append(silent, responsive) {
+		select {
+		case <-sess.NotifyClosed():
+		case <-time.After(time.Second):
+			t.Errorf("session %s was not closed in time", sess)
+		}
+	}
+}
+
+func TestServerConfigureBind(t *testing.T) {
+	binds := make(chan smpp.BindRequest, 1)
+	goldBound := make(chan struct{})
+	goldHandler := smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
+		switch ctx.CommandID() {
+		case pdu.BindTransceiverID:
+			btrx, _ := ctx.BindTRx()
+			ctx.Respond(btrx.Response("TestingServer"), pdu.StatusOK)
+			close(goldBound)
+		case pdu.EnquireLinkID:
+			el, _ := ctx.EnquireLink()
+			ctx.Respond(el.Response(), pdu.StatusOK)
+		}
+	})
+	sessConf := smpp.SessionConf{
+		RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
+			t.Errorf("default handler should be replaced, got %s", ctx.CommandID())
+		}),
+		ConfigureBind: func(req smpp.BindRequest, conf smpp.SessionConf) smpp.SessionConf {
+			binds <- req
+			if req.SystemID == "Client" {
+				conf.RequestHandler = goldHandler
+				conf.ReqRateLimit = 2
+			}
+			return conf
+		},
+	}
+	srv := smpp.NewServer("", sessConf)
+	addr, _ := serve(t, srv)
+	defer srv.Close()
+	statuses := make(chan pdu.Status, 3)
+	sess := bindToServerConf(addr, smpp.SessionConf{
+		ResponseHandler: smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {
+			if ctx.CommandID() != pdu.BindTransceiverRespID {
+				statuses <- ctx.Header().Status()
+			}
+		}),
+	})
+	defer sess.Close()
+	select {
+	case req := <-binds:
+		if req.SystemID != "Client" || req.CommandID != pdu.BindTransceiverID || req.RemoteAddr == "" {
+			t.Errorf("unexpected bind request %+v", req)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ConfigureBind was not called")
+	}
+	select {
+	case <-goldBound:
+	case <-time.After(time.Second):
+		t.Fatal("bind was not handled by configured handler")
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := sess.SendRequest(context.Background(), pdu.EnquireLink{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	throttled := 0
+	for i := 0; i < 3; i++ {
+		select {
+		case st := <-statuses:
+			if st == pdu.StatusThrottled {
+				throttled++
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for enquire_link response")
+		}
+	}
+	if throttled != 2 {
+		t.Errorf("expected 2 throttled requests got %d", throttled)
+	}
 }
@@ -1,6 +1,6 @@
 package smpp
 
-//go:generate stringer -type=SessionState,SessionType
+//go:generate stringer -type=SessionState,SessionType,CloseReason
 
 import (
 	"context"
@@ -11,6 +11,7 @@ import (
 	"log"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/majiddarvishan/smpp/pdu"
@@ -72,7 +73,30 @@ const (
 	SMSC
 )
 
+// CloseReason describes why the session was closed. It is reported to the
+// SessionState hook together with the StateClosing and StateClosed states.
+type CloseReason int
+
+const (
+	// CloseReasonNone is reported for states that are not related to closing.
+	CloseReasonNone CloseReason = iota
+	// CloseReasonLocal session was closed by calling Session.Close.
+	CloseReasonLocal
+	// CloseReasonConnection connection with the peer was lost or broken.
+	CloseReasonConnection
+	// CloseReasonHandler session was closed by the request or response handler.
+	CloseReasonHandler
+	// CloseReasonBindTimeout peer didn't bind within SessionConf.BindTimeout.
+	CloseReasonBindTimeout
+	// CloseReasonInactivity nothing was received from the peer within
+	// SessionConf.InactivityTimeout.
+	CloseReasonInactivity
+	// CloseReasonUnbind session was closed after receiving unbind_resp.
+	CloseReasonUnbind
+)
+
 // Logger provides logging interface for getting info about internals of smpp package.
+// See StructuredLogger for leveled logging with fields.
 type Logger interface {
 	InfoF(msg string, params ...interface{})
 	ErrorF(msg string, params ...interface{})
@@ -139,17 +163,46 @@ type RemoteAddresser interface {
 
 // SessionConf structured session configuration.
 type SessionConf struct {
-	Type            SessionType
-	SendWinSize     int
-	ReqWinSize      int
-	WindowTimeout   time.Duration
-	SessionState    func(sessionID, systemID string, state SessionState)
-	SystemID        string
-	ID              string
-	Logger          Logger
+	Type          SessionType
+	SendWinSize   int
+	ReqWinSize    int
+	WindowTimeout time.Duration
+	// BindTimeout is the maximum duration session can stay open without being
+	// bound. Session is closed with CloseReasonBindTimeout once it elapses.
+	// Zero value disables the check.
+	BindTimeout time.Duration
+	// InactivityTimeout is the maximum duration without receiving any PDU
+	// from the peer, enquire_link included. Session is closed with
+	// CloseReasonInactivity once it elapses. Zero value disables the check.
+	InactivityTimeout time.Duration
+	SessionState      func(sessionID, systemID string, state SessionState, reason CloseReason)
+	SystemID          string
+	ID                string
+	Logger            Logger
+	// StructuredLogger receives the session log entries. Defaults to
+	// passing entries at info level and above to Logger.
+	StructuredLogger StructuredLogger
+	// LogSensitive logs passwords and message content in PDU bodies, which
+	// are redacted by default.
+	LogSensitive bool
+	// Metrics receives session measurements. Defaults to discarding them.
+	Metrics Metrics
+	// Tracer receives the spans of sends, receives and handler executions.
+	// Defaults to discarding them.
+	Tracer          Tracer
 	RequestHandler  Handler
 	ResponseHandler Handler
 	Sequencer       pdu.Sequencer
+	// ReqRateLimit is the maximum number of requests per second accepted from
+	// the peer, bind included. Requests above the limit are throttled.
+	// Zero value disables it.
+	ReqRateLimit int
+	// ConfigureBind is called when SMSC session receives bind request, before
+	// it is handled by RequestHandler. It receives current configuration and
+	// returns configuration to be used for the rest of the session. Only window
+	// sizes, timeouts, rate limit and handlers are taken from the returned value.
+	// It is called without holding the session lock and may use the session.
+	ConfigureBind func(req BindRequest, conf SessionConf) SessionConf
 	// MapResetInterval specifies the duration after which the session's map will be recreated
 	// to mitigate potential memory growth. Setting this to a positive duration can help
 	// manage memory usage, especially when large amounts of data are added and removed from the map.
@@ -162,6 +215,15 @@ type response struct {
 	err  error
 }
 
+// sentRequest is a request waiting for its response.
+type sentRequest struct {
+	l    chan response
+	id   pdu.CommandID
+	at   time.Time
+	ctx  context.Context // of the send span
+	span Span
+}
+
 // Session is the engine that coordinates SMPP protocol for bounded peers.
 type Session struct {
 	conf     *SessionConf
@@ -172,10 +234,26 @@ type Session struct {
 	mu       sync.Mutex
 	seq      uint32
 	reqCount int
-	sent     map[uint32]chan response
+	sent     map[uint32]sentRequest
 	state    SessionState
-	systemID string
+	systemID atomic.Value // string, read by timers and handlers without mu
 	closed   chan struct{}
+	reason   CloseReason
+	unbound  bool // unbind_resp was received
+	bindTmr  *time.Timer
+	idleTmr  *time.Timer
+	limiter  *rateLimiter
+	stats    SessionStats
+	respTime time.Duration // sum of the response times of stats.Responses
+}
+
+// BindRequest describes bind request received from the peer.
+type BindRequest struct {
+	SystemID   string
+	SystemType string
+	// CommandID is one of the bind command IDs and identifies bind type.
+	CommandID  pdu.CommandID
+	RemoteAddr string
 }
 
 // NewSession creates new SMPP session and starts goroutine for listening incoming
@@ -190,6 +268,15 @@ func NewSession(rwc io.ReadWriteCloser, conf SessionConf) *Session {
 	if conf.Logger == nil {
 		conf.Logger = DefaultLogger{}
 	}
+	if conf.StructuredLogger == nil {
+		conf.StructuredLogger = NewPrintfLogger(conf.Logger, LevelInfo)
+	}
+	if conf.Metrics == nil {
+		conf.Metrics = nopMetrics{}
+	}
+	if conf.Tracer == nil {
+		conf.Tracer = nopTracer{}
+	}
 	if conf.RequestHandler == nil {
 		conf.RequestHandler = &defaultHandler{}
 	}
@@ -210,14 +297,24 @@ func NewSession(rwc io.ReadWriteCloser, conf SessionConf) *Session {
 		conf.MapResetInterval = time.Hour * 12
 	}
 	sess := &Session{
-		conf:   &conf,
-		RWC:    rwc,
-		enc:    pdu.NewEncoder(conf.Sequencer),
-		dec:    pdu.NewDecoder(),
-		sent:   make(map[uint32]chan response, conf.SendWinSize),
-		closed: make(chan struct{}),
+		conf:    &conf,
+		RWC:     rwc,
+		enc:     pdu.NewEncoder(conf.Sequencer),
+		dec:     pdu.NewDecoder(),
+		sent:    make(map[uint32]sentRequest, conf.SendWinSize),
+		closed:  make(chan struct{}),
+		limiter: newRateLimiter(conf.ReqRateLimit),
+		stats:   SessionStats{Opened: time.Now()},
 	}
+	// Timers are armed before serving so the read loop can safely reset them.
+	sess.mu.Lock()
+	if conf.BindTimeout > 0 {
+		sess.bindTmr = time.AfterFunc(conf.BindTimeout, sess.checkBound)
+	}
+	sess.setInactivityTimeout(conf.InactivityTimeout)
 	sess.wg.Add(1)
+	sess.mu.Unlock()
+	conf.Metrics.SessionOpened(conf.ID, conf.Type)
 	go sess.serve()
 	go sess.resetSentMapPeriodically()
 	return sess
@@ -233,8 +330,8 @@ func (sess *Session) SystemID() string {
 	if sess.conf.SystemID != "" {
 		return sess.conf.SystemID
 	}
-	if sess.systemID != "" {
-		return sess.systemID
+	if id, _ := sess.systemID.Load().(string); id != "" {
+		return id
 	}
 	return "-"
 }
@@ -265,83 +362,110 @@ func (sess *Session) serve() {
 		// Read header first.
 		var headerBytes [16]byte
 		if _, err := io.ReadFull(sess.RWC, headerBytes[:]); err != nil {
-			if err == io.EOF {
-				sess.conf.Logger.InfoF("decoding pdu: %s %+v", sess, err)
-			} else {
-				sess.conf.Logger.ErrorF("decoding pdu: %s %+v", sess, err)
-			}
-			sess.shutdown()
+			sess.logReadError(err)
+			sess.shutdown(CloseReasonConnection)
 			return
 		}
+		if sess.idleTmr != nil {
+			sess.idleTmr.Reset(sess.conf.InactivityTimeout)
+		}
 		h, p, err := sess.dec.DecodeHeader(headerBytes[:])
 		if err != nil {
-			if err == io.EOF {
-				sess.conf.Logger.InfoF("decoding pdu: %s %+v", sess, err)
-			} else {
-				sess.conf.Logger.ErrorF("decoding pdu: %s %+v", sess, err)
-			}
-			sess.shutdown()
+			sess.logReadError(err)
+			sess.shutdown(CloseReasonConnection)
 			return
 		}
+		sess.conf.Metrics.PDUReceived(h.CommandID(), h.Status())
+		spanCtx, span := sess.startSpan(sess.receiveParent(ctx, h), SpanReceive, h.CommandID(), h.Sequence())
 		if h.Length() > 16 {
 			bodyBytes := make([]byte, h.Length()-16)
 			if len(bodyBytes) > 0 {
 				if _, err := io.ReadFull(sess.RWC, bodyBytes); err != nil {
-					// sess.conf.Logger.Errorf("smpp: pdu length doesn't match read body length %d != %d", h.Length(), len(bodyBytes))
-					sess.conf.Logger.ErrorF("smpp: pdu length doesn't match read body length %d != %d", h.Length(), len(bodyBytes))
-					sess.shutdown()
+					sess.logAt(LevelError, "reading pdu body", Field{FieldCommand, h.CommandID()}, Field{FieldSeq, h.Sequence()}, Field{FieldError, err})
+					sess.endSpan(spanCtx, span, h.Status(), err)
+					sess.shutdown(CloseReasonConnection)
 					return
 				}
 			}
 			// Unmarshal binary
 			if err := p.UnmarshalBinary(bodyBytes); err != nil {
-				if err == io.EOF {
-					sess.conf.Logger.InfoF("decoding pdu: %s %+v", sess, err)
-				} else {
-					sess.conf.Logger.ErrorF("decoding pdu: %s %+v", sess, err)
-				}
-				sess.shutdown()
+				sess.logReadError(err)
+				sess.endSpan(spanCtx, span, h.Status(), err)
+				sess.shutdown(CloseReasonConnection)
 				return
 			}
 		}
 
 		sess.mu.Lock()
+		sess.stats.BytesIn += uint64(h.Length())
+		sess.stats.PDUsIn++
+		sess.stats.LastActivity = time.Now()
 
 		// todo: I have to do better implementation
 		switch h.CommandID() {
 		case pdu.BindTransceiverID, pdu.BindTransmitterID, pdu.BindReceiverID:
-			sess.systemID = pdu.SystemID(p)
+			sess.systemID.Store(pdu.SystemID(p))
 		}
 
 		if err := sess.makeTransition(h.CommandID(), true); err != nil {
-			sess.conf.Logger.ErrorF("transitioning upon receive: %s %+v", sess, err)
+			sess.logAt(LevelError, "transitioning upon receive", Field{FieldCommand, h.CommandID()}, Field{FieldSeq, h.Sequence()}, Field{FieldError, err})
+			sess.endSpan(spanCtx, span, h.Status(), err)
 			sess.mu.Unlock()
 			continue
 		}
+		if sess.conf.Type == SMSC && sess.state == StateBinding && sess.conf.ConfigureBind != nil {
+			// The hook runs unlocked so it may use the session.
+			req, conf := sess.bindRequest(p), *sess.conf
+			sess.mu.Unlock()
+			conf = conf.ConfigureBind(req, conf)
+			sess.mu.Lock()
+			if sess.state != StateBinding {
+				// Closed while the hook ran.
+				sess.endSpan(spanCtx, span, h.Status(), nil)
+				sess.mu.Unlock()
+				continue
+			}
+			sess.configureBind(conf)
+		}
 		// Handle PDU requests.
 		if pdu.IsRequest(h.CommandID()) {
-			// sess.conf.Logger.InfoF("received request: %s %s%+v, header: %#v", sess, p.CommandID(), p, h)
-			sess.conf.Logger.InfoF("received request: %s %s, \nheader:\n%vbody\n%+v", sess, p.CommandID(), h, p)
-			if sess.reqCount == sess.conf.ReqWinSize {
-				sess.throttle(h.Sequence())
+			sess.logPDU("received request", h.Sequence(), h.Status(), p)
+			if sess.reqCount == sess.conf.ReqWinSize || !sess.limiter.allow(time.Now()) {
+				sess.throttle(spanCtx, h.Sequence())
 			} else {
 				sess.wg.Add(1)
 				sess.reqCount++
-				go sess.handleRequest(ctx, h, p)
+				sess.conf.Metrics.Windows(sess.conf.ID, len(sess.sent), sess.reqCount)
+				go sess.handleRequest(spanCtx, h, p)
 			}
+			sess.endSpan(spanCtx, span, h.Status(), nil)
 			sess.mu.Unlock()
 			continue
 		}
 		// Handle PDU responses.
 		// if l, ok := sess.sent[h.Sequence()]; ok {
-		if _, ok := sess.sent[h.Sequence()]; ok {
-			// sess.conf.Logger.InfoF("received response: %s %s%+v, header: %#v", sess, p.CommandID(), p, h)
-			sess.conf.Logger.InfoF("received response: %s %s, \nheader:\n%vbody\n%+v", sess, p.CommandID(), h, p)
+		if req, ok := sess.sent[h.Sequence()]; ok {
+			sess.logPDU("received response", h.Sequence(), h.Status(), p)
 			delete(sess.sent, h.Sequence())
+			latency := time.Since(req.at)
+			sess.respTime += latency
+			sess.stats.Responses++
+			if h.Status() != pdu.StatusOK {
+				sess.stats.Failed++
+			}
+			sess.conf.Metrics.ResponseLatency(req.id, h.Status(), latency)
+			sess.conf.Metrics.Windows(sess.conf.ID, len(sess.sent), sess.reqCount)
+			sess.endSpan(req.ctx, req.span, h.Status(), nil)
 
 			sess.wg.Add(1)
-			go sess.handleResponse(ctx, h, p)
+			go sess.handleResponse(spanCtx, h, p)
+			sess.endSpan(spanCtx, span, h.Status(), nil)
 
+			// Unbinding is finished, close once handlers are done.
+			if h.CommandID() == pdu.UnbindRespID && sess.state == StateUnbinding {
+				sess.unbound = true
+				sess.shutdown(CloseReasonUnbind)
+			}
 			sess.mu.Unlock()
 
 			// l <- response{
@@ -351,23 +475,131 @@ func (sess *Session) serve() {
 			// }
 			continue
 		}
-		sess.conf.Logger.InfoF("unexpected response: %s %s%+v, header: %#v", sess, p.CommandID(), p, h)
+		sess.logPDU("unexpected response", h.Sequence(), h.Status(), p)
+		sess.endSpan(spanCtx, span, h.Status(), nil)
 		sess.mu.Unlock()
 	}
 }
 
-func (sess *Session) throttle(seq uint32) {
+// bindRequest describes bind request p for the ConfigureBind hook.
+//
+// Must be guarded by mutex.
+func (sess *Session) bindRequest(p pdu.PDU) BindRequest {
+	req := BindRequest{
+		SystemID:   pdu.SystemID(p),
+		CommandID:  p.CommandID(),
+		RemoteAddr: sess.remoteAddr(),
+	}
+	switch b := p.(type) {
+	case *pdu.BindTRx:
+		req.SystemType = b.SystemType
+	case *pdu.BindTx:
+		req.SystemType = b.SystemType
+	case *pdu.BindRx:
+		req.SystemType = b.SystemType
+	}
+	return req
+}
+
+// configureBind applies per bind configuration returned by ConfigureBind hook.
+//
+// Must be guarded by mutex.
+func (sess *Session) configureBind(conf SessionConf) {
+	if conf.SendWinSize > 0 {
+		sess.conf.SendWinSize = conf.SendWinSize
+	}
+	if conf.ReqWinSize > 0 {
+		sess.conf.ReqWinSize = conf.ReqWinSize
+	}
+	if conf.WindowTimeout > 0 {
+		sess.conf.WindowTimeout = conf.WindowTimeout
+	}
+	if conf.InactivityTimeout != sess.conf.InactivityTimeout {
+		sess.setInactivityTimeout(conf.InactivityTimeout)
+	}
+	if conf.ReqRateLimit != sess.conf.ReqRateLimit {
+		sess.conf.ReqRateLimit = conf.ReqRateLimit
+		sess.limiter = newRateLimiter(conf.ReqRateLimit)
+	}
+	if conf.RequestHandler != nil {
+		sess.conf.RequestHandler = conf.RequestHandler
+	}
+	if conf.ResponseHandler != nil {
+		sess.conf.ResponseHandler = conf.ResponseHandler
+	}
+}
+
+// Must be guarded by mutex.
+func (sess *Session) setInactivityTimeout(d time.Duration) {
+	sess.conf.InactivityTimeout = d
+	if sess.idleTmr != nil {
+		sess.idleTmr.Stop()
+		sess.idleTmr = nil
+	}
+	if d > 0 {
+		sess.idleTmr = time.AfterFunc(d, func() {
+			sess.logAt(LevelInfo, "inactivity timeout")
+			sess.shutdown(CloseReasonInactivity)
+		})
+	}
+}
+
+// rateLimiter is a token bucket allowing up to rate requests per second.
+// Nil limiter allows everything.
+type rateLimiter struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+	}
+}
+
+func (rl *rateLimiter) allow(now time.Time) bool {
+	if rl == nil {
+		return true
+	}
+	if !rl.last.IsZero() {
+		rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+		if rl.tokens > rl.rate {
+			rl.tokens = rl.rate
+		}
+	}
+	rl.last = now
+	if rl.tokens < 1 {
+		return false
+	}
+	rl.tokens--
+	return true
+}
+
+func (sess *Session) throttle(ctx context.Context, seq uint32) {
 	resp := pdu.GenericNack{}
+	spanCtx, span := sess.startSpan(ctx, SpanSend, resp.CommandID(), seq)
 	_, buf, err := sess.enc.Encode(resp, pdu.EncodeStatus(pdu.StatusThrottled), pdu.EncodeSeq(seq))
 	if err != nil {
-		sess.conf.Logger.ErrorF("error encoding pdu: %s %+v", sess, err)
+		sess.logAt(LevelError, "encoding generic_nack", Field{FieldSeq, seq}, Field{FieldError, err})
+		sess.endSpan(spanCtx, span, pdu.StatusThrottled, err)
 		return
 	}
 
 	_, err = sess.RWC.Write(buf)
+	sess.endSpan(spanCtx, span, pdu.StatusThrottled, err)
 	if err != nil {
-		sess.conf.Logger.ErrorF("error sending GenericNack: %s %+v", sess, err)
+		sess.logAt(LevelError, "sending generic_nack", Field{FieldSeq, seq}, Field{FieldError, err})
+		return
 	}
+	sess.sentBytes(len(buf))
+	sess.stats.Throttled++
+	sess.conf.Metrics.PDUSent(resp.CommandID(), pdu.StatusThrottled)
+	sess.logPDU("throttled request", seq, pdu.StatusThrottled, resp)
 }
 
 func (sess *Session) handleRequest(ctx context.Context, h pdu.Header, req pdu.PDU) {
@@ -376,20 +608,23 @@ func (sess *Session) handleRequest(ctx context.Context, h pdu.Header, req pdu.PD
 		cancel()
 		sess.mu.Lock()
 		sess.reqCount--
+		sess.conf.Metrics.Windows(sess.conf.ID, len(sess.sent), sess.reqCount)
 		sess.mu.Unlock()
 		sess.wg.Done()
 	}()
+	spanCtx, span := sess.startSpan(ctx, SpanRequestHandler, h.CommandID(), h.Sequence())
 	sessCtx := &Context{
 		Sess: sess,
-		ctx:  ctx,
+		ctx:  spanCtx,
 		seq:  h.Sequence(),
 		hdr:  h,
 		pdu:  req,
 	}
 	sess.conf.RequestHandler.ServeSMPP(sessCtx)
+	sess.endSpan(spanCtx, span, sessCtx.status, nil)
 
 	if sessCtx.close {
-		sess.shutdown()
+		sess.shutdown(CloseReasonHandler)
 	}
 }
 
@@ -401,36 +636,73 @@ func (sess *Session) handleResponse(ctx context.Context, h pdu.Header, resp pdu.
 		sess.wg.Done()
 	}()
 
+	spanCtx, span := sess.startSpan(ctx, SpanResponseHandler, h.CommandID(), h.Sequence())
 	sessCtx := &Context{
 		Sess: sess,
-		ctx:  ctx,
+		ctx:  spanCtx,
 		seq:  h.Sequence(),
 		hdr:  h,
 		pdu:  resp,
 	}
 
 	sess.conf.ResponseHandler.ServeSMPP(sessCtx)
+	sess.endSpan(spanCtx, span, h.Status(), nil)
 
 	if sessCtx.close {
-		sess.shutdown()
+		sess.shutdown(CloseReasonHandler)
 	}
 }
 
-func (sess *Session) shutdown() {
-	go sess.Close()
+func (sess *Session) shutdown(reason CloseReason) {
+	go sess.close(reason)
+}
+
+// checkBound closes the session if it didn't reach one of the bound states
+// before BindTimeout elapsed.
+func (sess *Session) checkBound() {
+	sess.mu.Lock()
+	state := sess.state
+	sess.mu.Unlock()
+	switch state {
+	case StateOpen, StateBinding:
+		sess.logAt(LevelInfo, "bind timeout")
+		sess.close(CloseReasonBindTimeout)
+	}
 }
 
 // Close implements Closer interface. It MUST be called to dispose session cleanly.
 // It gracefully waits for all handlers to finish execution before returning.
+// Closing a session that already closed itself after unbind returns nil.
 func (sess *Session) Close() error {
+	return sess.close(CloseReasonLocal)
+}
+
+func (sess *Session) close(reason CloseReason) error {
 	sess.mu.Lock()
+	if sess.state == StateClosing || sess.state == StateClosed {
+		unbound := sess.unbound && reason == CloseReasonLocal
+		sess.mu.Unlock()
+		if unbound {
+			// Session closed itself after unbind_resp, closing it again is harmless.
+			return nil
+		}
+		return fmt.Errorf("smpp: session %s already in %s state", sess, sess.state)
+	}
+	sess.reason = reason
+	if sess.bindTmr != nil {
+		sess.bindTmr.Stop()
+	}
+	if sess.idleTmr != nil {
+		sess.idleTmr.Stop()
+	}
 	if err := sess.setState(StateClosing); err != nil {
 		sess.mu.Unlock()
 		return err
 	}
-	for k, l := range sess.sent {
+	for k, req := range sess.sent {
 		delete(sess.sent, k)
-		close(l)
+		close(req.l)
+		sess.endSpan(req.ctx, req.span, 0, SessionClosedBeforeReceiving)
 	}
 	sess.RWC.Close()
 	if err := sess.setState(StateClosed); err != nil {
@@ -439,22 +711,32 @@ func (sess *Session) Close() error {
 	}
 	sess.mu.Unlock()
 	sess.wg.Wait()
-	sess.conf.Logger.InfoF("session closed: %s", sess)
+	sess.conf.Metrics.SessionClosed(sess.conf.ID, sess.conf.Type, reason)
+	sess.logAt(LevelInfo, "session closed", Field{FieldReason, reason})
 	close(sess.closed)
 	return nil
 }
 
+func (sess *Session) bound() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	switch sess.state {
+	case StateBoundTx, StateBoundRx, StateBoundTRx:
+		return true
+	}
+	return false
+}
+
 // Must be guarded by mutex.
 func (sess *Session) setState(state SessionState) error {
-	sess.conf.Logger.InfoF("smpp: old state (%s) wants change to new state (%s)", sess.state.String(), state.String())
+	sess.logAt(LevelDebug, "changing state", Field{"from", sess.state}, Field{FieldState, state})
 
 	if sess.state == state {
 		return fmt.Errorf("smpp: setting same state twice %s", state)
 	}
 	switch sess.state {
 	case StateOpen:
-		// if state != StateBinding && state != StateClosing {
-		if state != StateBinding {
+		if state != StateBinding && state != StateClosing {
 			return fmt.Errorf("smpp: setting open session to invalid state %s", state)
 		}
 	case StateBinding:
@@ -482,8 +764,17 @@ func (sess *Session) setState(state SessionState) error {
 		return fmt.Errorf("smpp: session %s already in closed state %s", sess, state)
 	}
 	sess.state = state
+	switch state {
+	case StateBoundTx, StateBoundRx, StateBoundTRx:
+		sess.stats.BoundSince = time.Now()
+		sess.stats.BindType = state
+	}
 	if hook := sess.conf.SessionState; hook != nil {
-		hook(sess.conf.ID, sess.SystemID(), sess.state)
+		reason := CloseReasonNone
+		if state == StateClosing || state == StateClosed {
+			reason = sess.reason
+		}
+		hook(sess.conf.ID, sess.SystemID(), sess.state, reason)
 	}
 	return nil
 }
@@ -499,30 +790,36 @@ func (sess *Session) SendRequest(ctx context.Context, req pdu.PDU, opts ...pdu.E
 		sess.mu.Unlock()
 		return 0, Error{Msg: "smpp: sending window closed", Temp: true}
 	}
+	spanCtx, span := sess.startSpan(ctx, SpanSend, req.CommandID(), 0)
 	if err := sess.makeTransition(req.CommandID(), false); err != nil {
-		sess.conf.Logger.ErrorF("transitioning before send: %s %+v", sess, err)
+		sess.logAt(LevelError, "transitioning before send", Field{FieldCommand, req.CommandID()}, Field{FieldError, err})
+		sess.endSpan(spanCtx, span, 0, err)
 		sess.mu.Unlock()
 		return 0, err
 	}
 	seq, buf, err := sess.enc.Encode(req, opts...)
 	if err != nil {
+		sess.endSpan(spanCtx, span, 0, err)
 		sess.mu.Unlock()
 		return 0, err
 	}
+	span.Seq = seq
 	l := make(chan response, 1)
-	sess.sent[seq] = l
+	sess.sent[seq] = sentRequest{l: l, id: req.CommandID(), at: span.Start, ctx: spanCtx, span: span}
 
 	_, err = sess.RWC.Write(buf)
 	if err != nil {
 		delete(sess.sent, seq)
+		sess.endSpan(spanCtx, span, 0, err)
 
 		sess.mu.Unlock()
 		return 0, err
 	}
+	sess.sentBytes(len(buf))
+	sess.conf.Metrics.PDUSent(req.CommandID(), pdu.StatusOK)
+	sess.conf.Metrics.Windows(sess.conf.ID, len(sess.sent), sess.reqCount)
 
-	// sess.conf.Logger.InfoF("request sent: %s %s%+v", sess, req.CommandID(), req)
-	// sess.conf.Logger.InfoF("request sent: %s %s, \nheader:\n%vbody\n%+v", sess, req.CommandID(), ctx.hdr, req)
-	sess.conf.Logger.InfoF("request sent: %s %s, \nsequence: %d\nbody\n%+v", sess, req.CommandID(), seq, req)
+	sess.logPDU("sent request", seq, pdu.StatusOK, req)
 	sess.mu.Unlock()
 	return seq, nil
 	// select {
@@ -541,27 +838,32 @@ func (sess *Session) SendRequest(ctx context.Context, req pdu.PDU, opts ...pdu.E
 
 func (sess *Session) SendResponse(ctx *Context, resp pdu.PDU, status pdu.Status) error {
 	sess.mu.Lock()
+	spanCtx, span := sess.startSpan(ctx.ctx, SpanSend, resp.CommandID(), ctx.seq)
 	if err := sess.makeTransition(resp.CommandID(), false); err != nil {
-		sess.conf.Logger.ErrorF("transitioning resp pdu: %s %+v", sess, err)
+		sess.logAt(LevelError, "transitioning before send", Field{FieldCommand, resp.CommandID()}, Field{FieldSeq, ctx.seq}, Field{FieldError, err})
+		sess.endSpan(spanCtx, span, status, err)
 		sess.mu.Unlock()
 		return err
 	}
 
 	_, buf, err := sess.enc.Encode(resp, pdu.EncodeStatus(status), pdu.EncodeSeq(ctx.seq))
 	if err != nil {
-		sess.conf.Logger.ErrorF("error encoding pdu: %s %+v", sess, err)
+		sess.logAt(LevelError, "encoding pdu", Field{FieldCommand, resp.CommandID()}, Field{FieldSeq, ctx.seq}, Field{FieldError, err})
+		sess.endSpan(spanCtx, span, status, err)
 		sess.mu.Unlock()
 		return err
 	}
 
 	_, err = sess.RWC.Write(buf)
+	sess.endSpan(spanCtx, span, status, err)
 	if err != nil {
 		sess.mu.Unlock()
 		return err
 	}
+	sess.sentBytes(len(buf))
+	sess.conf.Metrics.PDUSent(resp.CommandID(), status)
 
-	// ctx.Sess.conf.Logger.InfoF("sent response: %s %s %+v", ctx.Sess, resp.CommandID(), resp)
-	sess.conf.Logger.InfoF("sent response: %s %s, \nheader:\n%vbody\n%+v", sess, resp.CommandID(), ctx, resp)
+	sess.logPDU("sent response", ctx.seq, status, resp)
 	sess.mu.Unlock()
 	return nil
 }
@@ -690,7 +992,7 @@ func (sess *Session) resetSentMapPeriodically() {
 			// This helps in releasing memory occupied by old map's buckets.
 			// For a deeper dive into the memory behavior of Go maps, you can refer to:
 			// https://teivah.medium.com/maps-and-memory-leaks-in-go-a85ebe6e7e69
-			newSent := make(map[uint32]chan response)
+			newSent := make(map[uint32]sentRequest)
 			for i, responses := range sess.sent {
 				newSent[i] = responses
 			}
@@ -702,8 +1004,17 @@ func (sess *Session) resetSentMapPeriodically() {
 
 func (sess *Session) ReleaseSequenceNumber(seq uint32) {
 	sess.mu.Lock()
-	delete(sess.sent, seq)
-	sess.mu.Unlock()
+	defer sess.mu.Unlock()
+	if sess.state == StateClosing || sess.state == StateClosed {
+		// Closing released every request, and reporting windows would
+		// bring back the metrics of the session.
+		return
+	}
+	if req, ok := sess.sent[seq]; ok {
+		delete(sess.sent, seq)
+		sess.endSpan(req.ctx, req.span, 0, errReleased)
+	}
+	sess.conf.Metrics.Windows(sess.conf.ID, len(sess.sent), sess.reqCount)
 }
 
 // StatusError implements error interface for SMPP status errors.
@@ -1,8 +1,8 @@
 package smpp_test
 
 import (
-	"bytes"
 	"context"
+	"net"
 	"testing"
 	"time"
 
@@ -30,54 +30,66 @@ func (ts *testSequencer) skipNext() {
 }
 
 type testEncoder struct {
-	buf *bytes.Buffer
 	enc *pdu.Encoder
 	seq *testSequencer
 }
 
 func newTestEncoder(i int) *testEncoder {
-	buf := bytes.NewBuffer(nil)
 	seq := &testSequencer{seq: uint32(i)}
 	return &testEncoder{
-		buf: buf,
 		seq: seq,
-		enc: pdu.NewEncoder(buf, seq),
+		enc: pdu.NewEncoder(seq),
 	}
 }
 
 // Encode by incrementing counter.
 func (te *testEncoder) i(p pdu.PDU, status ...pdu.Status) []byte {
-	te.buf.Reset()
 	st := pdu.StatusOK
 	if len(status) > 0 {
 		st = status[0]
 	}
-	_, err := te.enc.Encode(p, pdu.EncodeStatus(st))
+	_, out, err := te.enc.Encode(p, pdu.EncodeStatus(st))
 	if err != nil {
 		panic(err.Error())
 	}
-	out := make([]byte, te.buf.Len())
-	copy(out, te.buf.Bytes())
 	return out
 }
 
 // Encode by skipping increment.
 func (te *testEncoder) s(p pdu.PDU, status ...pdu.Status) []byte {
-	te.buf.Reset()
 	st := pdu.StatusOK
 	if len(status) > 0 {
 		st = status[0]
 	}
 	te.seq.skipNext()
-	_, err := te.enc.Encode(p, pdu.EncodeStatus(st))
+	_, out, err := te.enc.Encode(p, pdu.EncodeStatus(st))
 	if err != nil {
 		panic(err.Error())
 	}
-	out := make([]byte, te.buf.Len())
-	copy(out, te.buf.Bytes())
 	return out
 }
 
+// responses collects the responses passed to the response handler.
+type responses chan *smpp.Context
+
+func (r responses) handler() smpp.Handler {
+	return smpp.ResponseHandlerFunc(func(ctx *smpp.Context) {
+		r <- ctx
+	})
+}
+
+func (r responses) wait(t *testing.T, id pdu.CommandID, status pdu.Status) {
+	t.Helper()
+	select {
+	case ctx := <-r:
+		if ctx.CommandID() != id || ctx.Header().Status() != status {
+			t.Errorf("expected %s %s got %s %s", id, status, ctx.CommandID(), ctx.Header().Status())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for %s", id)
+	}
+}
+
 func TestESMESession(t *testing.T) {
 	bindTRx := &pdu.BindTRx{
 		SystemID:         "ESME",
@@ -104,35 +116,31 @@ func TestESMESession(t *testing.T) {
 		ByteWrite(e.i(unbind)).ByteRead(e.s(unbindResp)).
 		Wait(1).
 		Closed()
+	resps := make(responses, 1)
 	conf := smpp.SessionConf{
-		SystemID: "TestingESME",
+		SystemID:        "TestingESME",
+		ResponseHandler: resps.handler(),
 	}
 	sess := smpp.NewSession(conn, conf)
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
-	_, resp, err := sess.Send(ctx, bindTRx)
-	if err != nil {
+	if _, err := sess.SendRequest(ctx, bindTRx); err != nil {
 		t.Fatal(err)
 	}
-	if resp.CommandID() != pdu.BindTransceiverRespID {
-		t.Errorf("expected BindTransceiverRespID got %d", resp.CommandID())
-	}
-	_, resp, err = sess.Send(ctx, submitSm)
-	if err != nil {
+	resps.wait(t, pdu.BindTransceiverRespID, pdu.StatusOK)
+	if _, err := sess.SendRequest(ctx, submitSm); err != nil {
 		t.Fatal(err)
 	}
-	if resp.CommandID() != pdu.SubmitSmRespID {
-		t.Errorf("expected SubmitSmRespID got %d", resp.CommandID())
-	}
-	_, resp, err = sess.Send(ctx, unbind)
-	if err != nil {
+	resps.wait(t, pdu.SubmitSmRespID, pdu.StatusOK)
+	if _, err := sess.SendRequest(ctx, unbind); err != nil {
 		t.Fatal(err)
 	}
-	if resp.CommandID() != pdu.UnbindRespID {
-		t.Errorf("expected UnbindRespID got %d", resp.CommandID())
-	}
-	if err := sess.Close(); err != nil {
-		t.Errorf("Got error during session close %+v", err)
+	resps.wait(t, pdu.UnbindRespID, pdu.StatusOK)
+	// Session closes itself once unbind_resp is received.
+	select {
+	case <-sess.NotifyClosed():
+	case <-time.After(time.Second):
+		t.Fatal("session was not closed after unbind")
 	}
 	errors := conn.Validate()
 	for _, err := range errors {
@@ -157,32 +165,21 @@ func TestESMESessionInvalidStatus(t *testing.T) {
 		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSmResp, pdu.StatusInvDstAdr)).
 		Wait(1).
 		Closed()
-	conf := smpp.SessionConf{}
+	resps := make(responses, 1)
+	conf := smpp.SessionConf{
+		ResponseHandler: resps.handler(),
+	}
 	sess := smpp.NewSession(conn, conf)
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
-	_, resp, err := sess.Send(ctx, bindTRx)
-	if err != nil {
+	if _, err := sess.SendRequest(ctx, bindTRx); err != nil {
 		t.Fatal(err)
 	}
-	if resp.CommandID() != pdu.BindTransceiverRespID {
-		t.Errorf("expected BindTransceiverRespID got %d", resp.CommandID())
-	}
-	_, resp, err = sess.Send(ctx, submitSm)
-	if err == nil {
-		t.Errorf("Expected status error got nil")
-	}
-	if resp.CommandID() != pdu.SubmitSmRespID {
-		t.Errorf("expected SubmitSmRespID got %d", resp.CommandID())
-	}
-	if serr, ok := err.(smpp.StatusError); !ok {
-		t.Errorf("Expected StatusError type")
-	} else {
-		expected := "Invalid Destination Address '0xB'"
-		if serr.Error() != expected {
-			t.Errorf("Status error: %v, expected %s", err, expected)
-		}
+	resps.wait(t, pdu.BindTransceiverRespID, pdu.StatusOK)
+	if _, err := sess.SendRequest(ctx, submitSm); err != nil {
+		t.Fatal(err)
 	}
+	resps.wait(t, pdu.SubmitSmRespID, pdu.StatusInvDstAdr)
 	if err := sess.Close(); err != nil {
 		t.Errorf("Got error during session close %+v", err)
 	}
@@ -219,7 +216,7 @@ func TestSMSCSession(t *testing.T) {
 	conf := smpp.SessionConf{
 		SystemID: "TestingSMSC",
 		Type:     smpp.SMSC,
-		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+		RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
 			switch ctx.CommandID() {
 			case pdu.BindTransceiverID:
 				btrx, err := ctx.BindTRx()
@@ -256,3 +253,104 @@ func TestSMSCSession(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSessionBindTimeout(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	reasons := make(chan smpp.CloseReason, 10)
+	conf := smpp.SessionConf{
+		Type:        smpp.SMSC,
+		BindTimeout: 20 * time.Millisecond,
+		SessionState: func(_, _ string, state smpp.SessionState, reason smpp.CloseReason) {
+			if state == smpp.StateClosing {
+				reasons <- reason
+			}
+		},
+	}
+	sess := smpp.NewSession(local, conf)
+	select {
+	case reason := <-reasons:
+		if reason != smpp.CloseReasonBindTimeout {
+			t.Errorf("expected %s got %s", smpp.CloseReasonBindTimeout, reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("session was not closed after bind timeout")
+	}
+	select {
+	case <-sess.NotifyClosed():
+	case <-time.After(time.Second):
+		t.Fatal("session was not closed in time")
+	}
+}
+
+func TestSessionInactivityTimeout(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	reasons := make(chan smpp.CloseReason, 10)
+	conf := smpp.SessionConf{
+		Type:              smpp.SMSC,
+		InactivityTimeout: 50 * time.Millisecond,
+		SessionState: func(_, _ string, state smpp.SessionState, reason smpp.CloseReason) {
+			if state == smpp.StateClosing {
+				reasons <- reason
+			}
+		},
+	}
+	sess := smpp.NewSession(local, conf)
+	enc := pdu.NewEncoder(pdu.NewSequencer(1))
+	_, buf, err := enc.Encode(pdu.EnquireLink{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep session alive past the timeout by sending traffic.
+	for i := 0; i < 3; i++ {
+		time.Sleep(25 * time.Millisecond)
+		if _, err := remote.Write(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case reason := <-reasons:
+		if reason != smpp.CloseReasonInactivity {
+			t.Errorf("expected %s got %s", smpp.CloseReasonInactivity, reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("session was not closed after inactivity timeout")
+	}
+	<-sess.NotifyClosed()
+}
+
+func TestSessionConfigureBindUsesSession(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	var sess *smpp.Session
+	systemIDs := make(chan string, 1)
+	conf := smpp.SessionConf{
+		Type: smpp.SMSC,
+		ConfigureBind: func(req smpp.BindRequest, conf smpp.SessionConf) smpp.SessionConf {
+			// Session methods taking the lock must not deadlock.
+			systemIDs <- sess.Stats().SystemID
+			return conf
+		},
+		RequestHandler: smpp.RequestHandlerFunc(func(ctx *smpp.Context) {
+			btx, _ := ctx.BindTx()
+			ctx.Respond(btx.Response("SMSC"), pdu.StatusOK)
+		}),
+	}
+	sess = smpp.NewSession(local, conf)
+	defer sess.Close()
+	e := newTestEncoder(0)
+	if _, err := remote.Write(e.i(&pdu.BindTx{SystemID: "ESME"})); err != nil {
+		t.Fatal(err)
+	}
+	h, _, err := readPDU(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.CommandID() != pdu.BindTransmitterRespID || h.Status() != pdu.StatusOK {
+		t.Errorf("expected bind_transmitter_resp got %s %s", h.CommandID(), h.Status())
+	}
+	if id := <-systemIDs; id != "ESME" {
+		t.Errorf("expected system_id ESME in hook got %s", id)
+	}
+}
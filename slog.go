@@ -0,0 +1,51 @@
+//go:build go1.21
+// +build go1.21
+
+package smpp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// NewSlogLogger returns a StructuredLogger writing to l. LevelTrace is
+// logged at slog.LevelDebug-4 and fmt.Stringer values as their string.
+func NewSlogLogger(l *slog.Logger) StructuredLogger {
+	return slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (sl slogLogger) Enabled(level LogLevel) bool {
+	return sl.l.Enabled(context.Background(), slogLevel(level))
+}
+
+func (sl slogLogger) Log(level LogLevel, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		switch v := f.Value.(type) {
+		case error:
+			attrs[i] = slog.Any(f.Key, v)
+		case fmt.Stringer:
+			attrs[i] = slog.String(f.Key, v.String())
+		default:
+			attrs[i] = slog.Any(f.Key, v)
+		}
+	}
+	sl.l.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+func slogLevel(level LogLevel) slog.Level {
+	switch {
+	case level <= LevelTrace:
+		return slog.LevelDebug - 4
+	case level == LevelDebug:
+		return slog.LevelDebug
+	case level == LevelInfo:
+		return slog.LevelInfo
+	}
+	return slog.LevelError
+}
@@ -0,0 +1,53 @@
+//go:build go1.21
+// +build go1.21
+
+package smpp
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf syncBuffer
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug - 4})))
+	if !l.Enabled(LevelTrace) {
+		t.Fatal("trace disabled")
+	}
+	runLoggedBind(t, SessionConf{StructuredLogger: l})
+
+	out := buf.String()
+	for _, want := range []string{
+		"level=DEBUG msg=\"received request\" session_id=s1 system_id=esme command=BindTransmitterID seq=1",
+		"level=DEBUG msg=\"sent response\" session_id=s1 system_id=esme command=BindTransmitterRespID seq=1",
+		"level=INFO msg=\"session closed\"",
+		"Password: <redacted>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("log output contains the password:\n%s", out)
+	}
+}
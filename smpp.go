@@ -137,18 +137,19 @@ func BindTRx(sc SessionConf, bc BindConf) (*Session, error) {
 }
 
 // Unbind session will initiate session unbinding and close the session.
-// First it will try to notify peer with unbind request.
+// First it will try to notify peer with unbind request and then wait until
+// unbind_resp is received and session closes itself. If ctx has no deadline
+// it waits for at most 10 seconds.
 // If there was any error during unbinding an error will be returned.
-// Session will be closed even if there was an error during unbind.
+// Session will be closed even if there was an error during unbind, calling
+// Close afterwards is harmless.
 func Unbind(ctx context.Context, sess *Session) error {
-	defer func() {
-		sess.Close()
-	}()
-	_, err := sess.SendRequest(ctx, pdu.Unbind{})
-	if err != nil {
-		return err
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
 	}
-	return nil
+	return unbindSession(ctx, sess).Err
 }
 
 // SendGenericNack is a helper function for sending GenericNack PDU.
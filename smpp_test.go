@@ -1,7 +1,6 @@
 package smpp_test
 
 import (
-	"bytes"
 	"context"
 	"io"
 	"log"
@@ -15,30 +14,36 @@ import (
 
 type mockServer struct {
 	Addr    string
-	Respond func(c net.Conn, in pdu.PDU, i int) []byte
+	Respond func(c net.Conn, h pdu.Header, in pdu.PDU, i int) []byte
 }
 
-func startServer(server *mockServer, n int) {
+// startServer listens on server.Addr and serves n PDUs of the first accepted
+// connection. It returns the listening address and closes finished when done.
+func startServer(server *mockServer, n int) (string, <-chan struct{}) {
 	l, err := net.Listen("tcp", server.Addr)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer l.Close()
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer l.Close()
 
-	tcpConn, err := l.Accept()
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer tcpConn.Close()
+		tcpConn, err := l.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer tcpConn.Close()
 
-	for i := 0; i < n; i++ {
-		server.Serve(tcpConn, i)
-	}
+		for i := 0; i < n; i++ {
+			server.Serve(tcpConn, i)
+		}
+	}()
+	return l.Addr().String(), finished
 }
 
 func (this *mockServer) Serve(c net.Conn, i int) {
-	d := pdu.NewDecoder(c)
-	_, p, err := d.Decode()
+	h, p, err := readPDU(c)
 	if err != nil {
 		if err != io.EOF {
 			log.Fatalf("serve decode %v %d", err, i)
@@ -48,21 +53,35 @@ func (this *mockServer) Serve(c net.Conn, i int) {
 	if p == nil {
 		log.Fatal("decode returned nil")
 	}
-	res := this.Respond(c, p, i)
+	res := this.Respond(c, h, p, i)
 	if res == nil {
 		return
 	}
-	if _, err := c.Write(res); err != nil {
-		log.Fatalf("connection write %v", err)
+	// The peer may already be gone, e.g. after Unbind closed the session.
+	c.Write(res)
+}
+
+func readPDU(r io.Reader) (pdu.Header, pdu.PDU, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, nil, err
+	}
+	h, p, err := pdu.NewDecoder().DecodeHeader(hdr[:])
+	if err != nil {
+		return h, p, err
 	}
+	body := make([]byte, h.Length()-16)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return h, p, err
+	}
+	return h, p, p.UnmarshalBinary(body)
 }
 
-func newBindingServer() *mockServer {
-	b := &bytes.Buffer{}
-	e := pdu.NewEncoder(b, nil)
+func newBindingServer(answerUnbind bool) *mockServer {
+	e := pdu.NewEncoder(nil)
 	return &mockServer{
-		Addr: "localhost:2222",
-		Respond: func(c net.Conn, in pdu.PDU, i int) []byte {
+		Addr: "127.0.0.1:0",
+		Respond: func(c net.Conn, h pdu.Header, in pdu.PDU, i int) []byte {
 			var res pdu.PDU
 			switch in.CommandID() {
 			case pdu.BindTransceiverID:
@@ -71,36 +90,41 @@ func newBindingServer() *mockServer {
 					Options:  pdu.NewOptions().SetScInterfaceVersion(0x34),
 				}
 			case pdu.UnbindID:
+				if !answerUnbind {
+					return nil
+				}
 				res = &pdu.UnbindResp{}
 			}
-			b.Reset()
-			if _, err := e.Encode(res); err != nil {
+			_, b, err := e.Encode(res, pdu.EncodeSeq(h.Sequence()))
+			if err != nil {
 				panic("Can't encode pdu")
 			}
-			return b.Bytes()
+			return b
 		},
 	}
 }
 
-func TestBindingUnbinding(t *testing.T) {
-	finished := make(chan struct{})
-	server := newBindingServer()
-	go func() {
-		startServer(server, 2)
-		finished <- struct{}{}
-	}()
-	time.Sleep(time.Millisecond * 10)
-	conf := smpp.BindConf{
-		Addr: "localhost:2222",
-	}
-	sess, err := smpp.BindTRx(smpp.SessionConf{}, conf)
+func bindToMock(t *testing.T, server *mockServer, n int) (*smpp.Session, <-chan struct{}) {
+	addr, finished := startServer(server, n)
+	resps := make(responses, 1)
+	sess, err := smpp.BindTRx(smpp.SessionConf{ResponseHandler: resps.handler()}, smpp.BindConf{Addr: addr})
 	if err != nil {
-		t.Errorf("bind error %s", err)
+		t.Fatalf("bind error %s", err)
 	}
-	if sess.SystemID() != "testing" {
-		t.Errorf("Invalid SystemID after bind %s", sess.SystemID())
+	select {
+	case ctx := <-resps:
+		if resp, err := ctx.BindTRxResp(); err != nil || resp.SystemID != "testing" {
+			t.Errorf("Invalid bind response %+v %v", resp, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for bind response")
 	}
-	err = smpp.Unbind(context.Background(), sess)
+	return sess, finished
+}
+
+func TestBindingUnbinding(t *testing.T) {
+	sess, finished := bindToMock(t, newBindingServer(true), 2)
+	err := smpp.Unbind(context.Background(), sess)
 	if err != nil {
 		t.Errorf("unbind error %s", err)
 	}
@@ -109,6 +133,30 @@ func TestBindingUnbinding(t *testing.T) {
 	case <-time.After(100 * time.Millisecond):
 		t.Error("session close timeout")
 	}
+	// Documented pattern is Unbind followed by Close.
+	if err := sess.Close(); err != nil {
+		t.Errorf("Close() after Unbind() => %v, expected nil", err)
+	}
+	select {
+	case <-finished:
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("mock server didn't close")
+	}
+}
+
+func TestUnbindWithoutResponse(t *testing.T) {
+	sess, finished := bindToMock(t, newBindingServer(false), 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := smpp.Unbind(ctx, sess)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Unbind() => %v, expected %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-sess.NotifyClosed():
+	default:
+		t.Error("session not closed after Unbind() returned")
+	}
 	select {
 	case <-finished:
 	case <-time.After(100 * time.Millisecond):
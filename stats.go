@@ -0,0 +1,126 @@
+package smpp
+
+import "time"
+
+// SessionStats is a snapshot of session state and counters.
+type SessionStats struct {
+	ID         string
+	Type       SessionType
+	SystemID   string
+	RemoteAddr string
+	State      SessionState
+	// BindType is the bound state the session reached, StateBoundTx,
+	// StateBoundRx or StateBoundTRx. It stays set while unbinding and
+	// closing, and is StateOpen if the session never bound.
+	BindType   SessionState
+	Opened     time.Time
+	BoundSince time.Time // zero if the session never bound
+	BytesIn    uint64
+	BytesOut   uint64
+	PDUsIn     uint64
+	PDUsOut    uint64
+	// Outstanding is the number of requests sent waiting for a response.
+	Outstanding int
+	// Handling is the number of received requests being handled.
+	Handling int
+	// Throttled is the number of requests answered with ESME_RTHROTTLED
+	// because the receive window was full or the rate limit was reached.
+	Throttled uint64
+	// Responses is the number of responses received to requests sent, and
+	// Failed the number of them with a status other than ESME_ROK.
+	Responses uint64
+	Failed    uint64
+	// AvgResponseTime is the mean time from sending a request to receiving
+	// its response.
+	AvgResponseTime time.Duration
+	// FailureRate is Failed divided by Responses, zero without responses.
+	FailureRate  float64
+	LastActivity time.Time // of the last PDU received, zero if none
+}
+
+// Stats returns a snapshot of the session state and counters.
+func (sess *Session) Stats() SessionStats {
+	st, _ := sess.snapshot()
+	return st
+}
+
+// snapshot returns Stats together with the sum of the response times it
+// averages, taken under the same lock.
+func (sess *Session) snapshot() (SessionStats, time.Duration) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	st := sess.stats
+	st.ID = sess.conf.ID
+	st.Type = sess.conf.Type
+	st.SystemID = sess.SystemID()
+	st.RemoteAddr = sess.remoteAddr()
+	st.State = sess.state
+	st.Outstanding = len(sess.sent)
+	st.Handling = sess.reqCount
+	if st.Responses > 0 {
+		st.AvgResponseTime = sess.respTime / time.Duration(st.Responses)
+		st.FailureRate = float64(st.Failed) / float64(st.Responses)
+	}
+	return st, sess.respTime
+}
+
+// sentBytes counts a PDU written to the peer. Must be guarded by mutex.
+func (sess *Session) sentBytes(n int) {
+	sess.stats.BytesOut += uint64(n)
+	sess.stats.PDUsOut++
+}
+
+// ServerStats aggregates the stats of the sessions a server is serving.
+type ServerStats struct {
+	Sessions    []SessionStats
+	Bound       int // sessions in one of the bound states
+	BytesIn     uint64
+	BytesOut    uint64
+	PDUsIn      uint64
+	PDUsOut     uint64
+	Outstanding int
+	Handling    int
+	Throttled   uint64
+	Responses   uint64
+	Failed      uint64
+	// AvgResponseTime and FailureRate are over the responses of all
+	// sessions.
+	AvgResponseTime time.Duration
+	FailureRate     float64
+}
+
+// Stats returns the stats of every active session and their totals.
+func (srv *Server) Stats() ServerStats {
+	srv.mu.Lock()
+	sessions := make([]*Session, 0, len(srv.activeSess))
+	for sess := range srv.activeSess {
+		sessions = append(sessions, sess)
+	}
+	srv.mu.Unlock()
+
+	out := ServerStats{Sessions: make([]SessionStats, len(sessions))}
+	var respTime time.Duration
+	for i, sess := range sessions {
+		st, rt := sess.snapshot()
+		out.Sessions[i] = st
+		switch st.State {
+		case StateBoundTx, StateBoundRx, StateBoundTRx:
+			out.Bound++
+		}
+		out.BytesIn += st.BytesIn
+		out.BytesOut += st.BytesOut
+		out.PDUsIn += st.PDUsIn
+		out.PDUsOut += st.PDUsOut
+		out.Outstanding += st.Outstanding
+		out.Handling += st.Handling
+		out.Throttled += st.Throttled
+		out.Responses += st.Responses
+		out.Failed += st.Failed
+		respTime += rt
+	}
+	if out.Responses > 0 {
+		out.AvgResponseTime = respTime / time.Duration(out.Responses)
+		out.FailureRate = float64(out.Failed) / float64(out.Responses)
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package smpp
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+func TestSessionStats(t *testing.T) {
+	local, remote := net.Pipe()
+	sess := NewSession(local, SessionConf{
+		Type: SMSC,
+		ID:   "s1",
+		RequestHandler: RequestHandlerFunc(func(ctx *Context) {
+			bind, err := ctx.BindTx()
+			if err != nil {
+				t.Errorf("BindTx() error %v", err)
+				return
+			}
+			if err := ctx.Respond(bind.Response("smsc"), pdu.StatusOK); err != nil {
+				t.Errorf("Respond() error %v", err)
+			}
+		}),
+		ResponseHandler: ResponseHandlerFunc(func(ctx *Context) {}),
+	})
+	defer sess.Close()
+	srv := &Server{activeSess: map[*Session]struct{}{sess: {}}}
+
+	enc := pdu.NewEncoder(pdu.NewSequencer(1))
+	_, buf, err := enc.Encode(&pdu.BindTx{SystemID: "esme", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	var hdr [16]byte
+	if _, err := io.ReadFull(remote, hdr[:]); err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, int(hdr[3])-16)
+	if _, err := io.ReadFull(remote, body); err != nil {
+		t.Fatal(err)
+	}
+
+	// The counters are updated once the write returns.
+	var st SessionStats
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if st = sess.Stats(); st.PDUsOut == 1 && st.Handling == 0 {
+			break
+		}
+	}
+	if st.ID != "s1" || st.Type != SMSC || st.SystemID != "esme" {
+		t.Errorf("Stats() identity => %+v", st)
+	}
+	if st.State != StateBoundTx || st.BindType != StateBoundTx || st.BoundSince.IsZero() {
+		t.Errorf("Stats() bind => %v %v %v", st.State, st.BindType, st.BoundSince)
+	}
+	if st.PDUsIn != 1 || st.BytesIn != uint64(len(buf)) {
+		t.Errorf("Stats() in => %d PDUs %d bytes", st.PDUsIn, st.BytesIn)
+	}
+	if st.PDUsOut != 1 || st.BytesOut != uint64(len(hdr)+len(body)) {
+		t.Errorf("Stats() out => %d PDUs %d bytes", st.PDUsOut, st.BytesOut)
+	}
+	if st.LastActivity.IsZero() || st.Outstanding != 0 || st.Handling != 0 || st.Throttled != 0 {
+		t.Errorf("Stats() => %+v", st)
+	}
+
+	if st.Responses != 0 || st.AvgResponseTime != 0 || st.FailureRate != 0 {
+		t.Errorf("Stats() responses => %+v", st)
+	}
+
+	// Answer one enquire_link with ESME_ROK and one with ESME_RSYSERR.
+	for _, status := range []pdu.Status{pdu.StatusOK, pdu.StatusSysErr} {
+		// Writes to a pipe block until read.
+		go func() {
+			if _, err := sess.SendRequest(context.Background(), &pdu.EnquireLink{}); err != nil {
+				t.Errorf("SendRequest() error %v", err)
+			}
+		}()
+		if _, err := io.ReadFull(remote, hdr[:]); err != nil {
+			t.Fatal(err)
+		}
+		seq := binary.BigEndian.Uint32(hdr[12:])
+		time.Sleep(2 * time.Millisecond)
+		_, buf, err := enc.Encode(&pdu.EnquireLinkResp{}, pdu.EncodeSeq(seq), pdu.EncodeStatus(status))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := remote.Write(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if st = sess.Stats(); st.Responses == 2 {
+			break
+		}
+	}
+	if st.Responses != 2 || st.Failed != 1 || st.FailureRate != 0.5 || st.AvgResponseTime < 2*time.Millisecond {
+		t.Errorf("Stats() responses => %d failed %d rate %v avg %v", st.Responses, st.Failed, st.FailureRate, st.AvgResponseTime)
+	}
+
+	total := srv.Stats()
+	if len(total.Sessions) != 1 || total.Bound != 1 || total.PDUsIn != 3 || total.BytesOut != st.BytesOut {
+		t.Errorf("Server.Stats() => %+v", total)
+	}
+	if total.Responses != 2 || total.Failed != 1 || total.FailureRate != 0.5 || total.AvgResponseTime != st.AvgResponseTime {
+		t.Errorf("Server.Stats() responses => %+v", total)
+	}
+}
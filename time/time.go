@@ -22,89 +22,182 @@ const (
 	Relative
 )
 
+// Codec parses and formats SMPP times. Relative times are resolved
+// against its clock.
+type Codec struct {
+	// Now returns the current time. Defaults to time.Now.
+	Now func() gotime.Time
+}
+
+func (c Codec) now() gotime.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return gotime.Now()
+}
+
 // Parse converts bytestring representation of time from SMPP format
 // to standard time.Time. Relative layouts will be added to the current
 // time and returned as time.Time.
 func Parse(in []byte) (gotime.Time, error) {
-	l := len(in)
-	switch l {
-	case 1, 0:
-		// nil time
-		return gotime.Time{}, nil
-	case 12:
-		// simple seconds
-		return gotime.Parse("060102150405", string(in))
-	case 10:
-		// simple minutes
-		return gotime.Parse("0601021504", string(in))
-	case 16:
-		layoutIndicator := in[l-1]
-		switch layoutIndicator {
-		case 'R':
-			// Relative layout.
-			y := int((in[0]-48)*10 + (in[1] - 48))
-			mo := int((in[2]-48)*10 + (in[3] - 48))
-			d := int((in[4]-48)*10 + (in[5] - 48))
-			h := int((in[6]-48)*10 + (in[7] - 48))
-			mi := int((in[8]-48)*10 + (in[9] - 48))
-			s := int((in[10]-48)*10 + (in[11] - 48))
-			return gotime.Now().
-				AddDate(y, mo, d).
-				Add(time.Duration(h)*time.Hour +
-					time.Duration(mi)*time.Minute +
-					time.Duration(s)*time.Second), nil
-		case '-', '+':
-			// Absolute layout.
-			nn := int((in[13]-48)*10 + (in[14] - 48))
-			offset := nn * 900 // 15 min intervals in seconds.
-			if layoutIndicator == '-' {
-				offset = -offset
-			}
-			var loc *gotime.Location
-			if offset != 0 {
-				loc = gotime.FixedZone("Custom", offset)
-			} else {
-				loc = gotime.UTC
-			}
-			t, err := gotime.ParseInLocation("060102150405", string(in[:l-4]), loc)
-			if err != nil {
-				return time.Time{}, err
-			}
-			t = t.Add(time.Duration(in[12]-48) * 100 * time.Millisecond)
-			return t, nil
-		default:
-			return gotime.Time{}, fmt.Errorf("smpp/time: invalid layout length %s", in)
-		}
-	default:
-		return gotime.Time{}, fmt.Errorf("smpp/time: invalid layout length %s", in)
-	}
+	return Codec{}.Parse(in)
 }
 
 // Format converts time.Time into string representation defined by smpp
 // predefined layout.
 func Format(layout Layout, t gotime.Time) (string, error) {
+	return Codec{}.Format(layout, t)
+}
+
+// ParseError describes an invalid SMPP time.
+type ParseError struct {
+	Input  string
+	Offset int // of the first offending character
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("smpp/time: parsing %q at offset %d: %s", e.Input, e.Offset, e.Msg)
+}
+
+// Parse converts an SMPP time to time.Time, adding relative times to the
+// current time. Empty input gives the zero time.Time.
+//
+// Every character is checked: fields must be digits in range, the UTC
+// offset at most 48 quarter hours and the last character one of +, -
+// and R. YY is read as 20YY.
+func (c Codec) Parse(in []byte) (gotime.Time, error) {
+	v, err := parseValue(in)
+	if err != nil {
+		return gotime.Time{}, err
+	}
+	return v.Resolve(c.now()), nil
+}
+
+// Format converts t to the SMPP layout. Relative layouts hold the time
+// from now until t, which must not be in the past.
+func (c Codec) Format(layout Layout, t gotime.Time) (string, error) {
+	if layout == Relative {
+		now := c.now()
+		if t.Before(now) {
+			return "", fmt.Errorf("smpp/time: %s is before now %s", t, now)
+		}
+		y, mo, d, h, mi, s := diff(t, now)
+		return formatPeriod(Period{Years: y, Months: mo, Days: d, Hours: h, Minutes: mi, Seconds: s})
+	}
+	if y := t.Year(); y < 2000 || y > 2099 {
+		return "", fmt.Errorf("smpp/time: year %d is out of range 2000-2099", y)
+	}
 	switch layout {
 	case SimpleSeconds:
 		return t.Format("060102150405"), nil
 	case SimpleMinutes:
 		return t.Format("0601021504"), nil
-	case Relative:
-		y, mo, d, h, mi, s := diff(t, gotime.Now())
-		return fmt.Sprintf("%02d%02d%02d%02d%02d%02d000R", y, mo, d, h, mi, s), nil
 	case Absolute:
 		sign := "+"
 		_, z := t.Zone()
+		if z%900 != 0 {
+			return "", fmt.Errorf("smpp/time: UTC offset %ds is not a multiple of 15 minutes", z)
+		}
 		offset := z / 900
 		if offset < 0 {
 			sign = "-"
 			offset = -offset
 		}
+		if offset > 48 {
+			return "", fmt.Errorf("smpp/time: UTC offset %d quarter hours is out of range 00-48", offset)
+		}
 		return fmt.Sprintf("%s%d%02d%s", t.Format("060102150405"), t.Nanosecond()/100000000, offset, sign), nil
 	default:
 		return "", errors.New("smpp/time: invalid format layout")
 	}
 }
 
+// parseValue parses any SMPP time layout keeping its form.
+func parseValue(in []byte) (Time, error) {
+	fail := func(offset int, format string, args ...interface{}) (Time, error) {
+		return Time{}, &ParseError{Input: string(in), Offset: offset, Msg: fmt.Sprintf(format, args...)}
+	}
+	l := len(in)
+	switch l {
+	case 0:
+		return Time{}, nil
+	case 10, 12, 16:
+	default:
+		return fail(0, "length %d is none of 10, 12 and 16", l)
+	}
+	digits := l
+	if l == 16 {
+		digits = 15
+	}
+	for i := 0; i < digits; i++ {
+		if !isDigit(in[i]) {
+			return fail(i, "%q is not a digit", in[i])
+		}
+	}
+	num := func(i int) int {
+		return int(in[i]-'0')*10 + int(in[i+1]-'0')
+	}
+
+	if l == 16 && in[15] == 'R' {
+		if nn := num(13); nn != 0 {
+			return fail(13, "relative time has UTC offset %02d", nn)
+		}
+		return NewRelative(Period{
+			Years:   num(0),
+			Months:  num(2),
+			Days:    num(4),
+			Hours:   num(6),
+			Minutes: num(8),
+			Seconds: num(10),
+			Tenths:  int(in[12] - '0'),
+		}), nil
+	}
+
+	year, month, day, hour, min := 2000+num(0), num(2), num(4), num(6), num(8)
+	if month < 1 || month > 12 {
+		return fail(2, "month %02d is out of range 01-12", month)
+	}
+	if days := daysIn(gotime.Month(month), year); day < 1 || day > days {
+		return fail(4, "day %02d is out of range 01-%02d", day, days)
+	}
+	if hour > 23 {
+		return fail(6, "hour %02d is out of range 00-23", hour)
+	}
+	if min > 59 {
+		return fail(8, "minute %02d is out of range 00-59", min)
+	}
+	var sec, tenths int
+	if l >= 12 {
+		if sec = num(10); sec > 59 {
+			return fail(10, "second %02d is out of range 00-59", sec)
+		}
+	}
+	loc := gotime.UTC
+	if l == 16 {
+		tenths = int(in[12] - '0')
+		offset := num(13)
+		if offset > 48 {
+			return fail(13, "UTC offset %02d is out of range 00-48", offset)
+		}
+		switch in[15] {
+		case '+':
+		case '-':
+			offset = -offset
+		default:
+			return fail(15, "%q is none of +, - and R", in[15])
+		}
+		if offset != 0 {
+			loc = gotime.FixedZone("", offset*900)
+		}
+	}
+	return NewAbsolute(gotime.Date(year, gotime.Month(month), day, hour, min, sec, tenths*100000000, loc)), nil
+}
+
+func daysIn(month gotime.Month, year int) int {
+	return gotime.Date(year, month+1, 0, 0, 0, 0, 0, gotime.UTC).Day()
+}
+
 // Go supports only dif with hours so borrowing this from
 // https://stackoverflow.com/questions/36530251/golang-time-since-with-months-and-years
 func diff(a, b time.Time) (year, month, day, hour, min, sec int) {
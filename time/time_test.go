@@ -2,6 +2,7 @@ package time_test
 
 import (
 	"testing"
+	"testing/quick"
 	gotime "time"
 
 	"github.com/majiddarvishan/smpp/time"
@@ -120,3 +121,146 @@ func TestFormatRelative(t *testing.T) {
 		t.Errorf("format not expected %s", out)
 	}
 }
+
+func TestCodecRelativeClock(t *testing.T) {
+	now := gotime.Date(2024, gotime.February, 28, 23, 0, 0, 0, gotime.UTC)
+	c := time.Codec{Now: func() gotime.Time { return now }}
+	out, err := c.Parse([]byte("000001010000500R"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := gotime.Date(2024, gotime.March, 1, 0, 0, 0, 500000000, gotime.UTC); !out.Equal(expected) {
+		t.Errorf("Parse() => %s expected %s", out, expected)
+	}
+	s, err := c.Format(time.Relative, now.Add(36*gotime.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "000001120000000R" {
+		t.Errorf("Format() => %s", s)
+	}
+	if _, err := c.Format(time.Relative, now.Add(-gotime.Second)); err == nil {
+		t.Error("expected error formatting a past time as relative")
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		offset int
+	}{
+		{"0206102334291204", 15},
+		{"02061023342912a-", 14},
+		{"021310233429120-", 2},
+		{"020230233429120-", 4},
+		{"230229233429120-", 4},
+		{"020610243429120-", 6},
+		{"020610236029120-", 8},
+		{"020610233460120-", 10},
+		{"020610233429149+", 13},
+		{"000001000000004R", 13},
+		{"0206 02334", 4},
+		{"0", 0},
+	} {
+		_, err := time.Parse([]byte(tt.in))
+		perr, ok := err.(*time.ParseError)
+		if !ok {
+			t.Errorf("Parse(%q) error %v, expected *ParseError", tt.in, err)
+			continue
+		}
+		if perr.Offset != tt.offset {
+			t.Errorf("Parse(%q) error at offset %d, expected %d: %v", tt.in, perr.Offset, tt.offset, err)
+		}
+	}
+	if _, err := time.Parse([]byte("240229233429148+")); err != nil {
+		t.Errorf("Parse() of a leap day with offset 48 error %v", err)
+	}
+}
+
+func TestFormatStrict(t *testing.T) {
+	for _, d := range []gotime.Time{
+		gotime.Date(1999, gotime.June, 10, 0, 0, 0, 0, gotime.UTC),
+		gotime.Date(2002, gotime.June, 10, 0, 0, 0, 0, gotime.FixedZone("", 600)),
+		gotime.Date(2002, gotime.June, 10, 0, 0, 0, 0, gotime.FixedZone("", 13*3600)),
+	} {
+		if out, err := time.Format(time.Absolute, d); err == nil {
+			t.Errorf("Format(%s) => %s, expected error", d, out)
+		}
+	}
+}
+
+func TestAbsoluteRoundTripProperty(t *testing.T) {
+	f := func(sec uint32, tenths, quarters uint8, negative bool) bool {
+		offset := int(quarters % 49)
+		if negative {
+			offset = -offset
+		}
+		loc := gotime.FixedZone("", offset*900)
+		base := gotime.Date(2000, gotime.January, 1, 0, 0, 0, 0, loc)
+		d := base.Add(gotime.Duration(int64(sec)%(99*365*86400))*gotime.Second +
+			gotime.Duration(tenths%10)*100*gotime.Millisecond)
+		s, err := time.Format(time.Absolute, d)
+		if err != nil {
+			return false
+		}
+		out, err := time.Parse([]byte(s))
+		if err != nil || !out.Equal(d) {
+			return false
+		}
+		_, z := out.Zone()
+		v, err := time.ParseTime([]byte(s))
+		return err == nil && z == offset*900 && v.String() == s
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRelativeRoundTripProperty(t *testing.T) {
+	f := func(y, mo, d, h, mi, s, tenths uint8) bool {
+		p := time.Period{
+			Years:   int(y % 100),
+			Months:  int(mo % 100),
+			Days:    int(d % 100),
+			Hours:   int(h % 100),
+			Minutes: int(mi % 100),
+			Seconds: int(s % 100),
+			Tenths:  int(tenths % 10),
+		}
+		b, err := time.NewRelative(p).MarshalText()
+		if err != nil {
+			return false
+		}
+		v, err := time.ParseTime(b)
+		got, ok := v.Relative()
+		return err == nil && ok && got == p
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseNeverPanicsProperty(t *testing.T) {
+	f := func(in []byte) bool {
+		_, _ = time.Parse(in)
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+	g := func(digits [15]uint8, last uint8) bool {
+		in := make([]byte, 16)
+		for i, d := range digits {
+			in[i] = '0' + d%10
+		}
+		in[15] = "+-R"[last%3]
+		v, err := time.ParseTime(in)
+		if string(in[13:]) == "00-" {
+			in[15] = '+' // a zero offset is always written as +
+		}
+		return err != nil || v.String() == string(in)
+	}
+	if err := quick.Check(g, nil); err != nil {
+		t.Error(err)
+	}
+}
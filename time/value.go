@@ -0,0 +1,138 @@
+package time
+
+import (
+	"fmt"
+	gotime "time"
+)
+
+// Period is the YYMMDDhhmmss interval of a relative time.
+type Period struct {
+	Years, Months, Days, Hours, Minutes, Seconds int
+	Tenths                                       int // of a second
+}
+
+// PeriodOf splits d into days, hours, minutes, seconds and tenths.
+func PeriodOf(d gotime.Duration) Period {
+	s := int(d / gotime.Second)
+	return Period{
+		Days:    s / 86400,
+		Hours:   s % 86400 / 3600,
+		Minutes: s % 3600 / 60,
+		Seconds: s % 60,
+		Tenths:  int(d % gotime.Second / (100 * gotime.Millisecond)),
+	}
+}
+
+// AddTo returns t moved forward by p.
+func (p Period) AddTo(t gotime.Time) gotime.Time {
+	return t.AddDate(p.Years, p.Months, p.Days).Add(
+		gotime.Duration(p.Hours)*gotime.Hour +
+			gotime.Duration(p.Minutes)*gotime.Minute +
+			gotime.Duration(p.Seconds)*gotime.Second +
+			gotime.Duration(p.Tenths)*100*gotime.Millisecond)
+}
+
+// Time is the value of an SMPP time field such as schedule_delivery_time
+// or validity_period: null, an absolute time with its UTC offset, or a
+// period relative to when the SMSC receives the PDU. Decoded values keep
+// the form they were sent in. The zero Time is null.
+type Time struct {
+	abs      gotime.Time
+	rel      Period
+	relative bool
+}
+
+// NewAbsolute returns the absolute time t. Its zone offset is kept and
+// must be a multiple of 15 minutes. A zero t gives the null Time.
+func NewAbsolute(t gotime.Time) Time {
+	return Time{abs: t}
+}
+
+// NewRelative returns the time p after the SMSC receives the PDU.
+func NewRelative(p Period) Time {
+	return Time{rel: p, relative: true}
+}
+
+// IsZero reports whether t is null.
+func (t Time) IsZero() bool {
+	return !t.relative && t.abs.IsZero()
+}
+
+// IsRelative reports whether t is relative.
+func (t Time) IsRelative() bool {
+	return t.relative
+}
+
+// Absolute returns the time of an absolute t.
+func (t Time) Absolute() (gotime.Time, bool) {
+	return t.abs, !t.relative && !t.abs.IsZero()
+}
+
+// Relative returns the period of a relative t.
+func (t Time) Relative() (Period, bool) {
+	return t.rel, t.relative
+}
+
+// Resolve returns the moment t designates when the PDU is received at
+// now. It returns the zero time.Time for null.
+func (t Time) Resolve(now gotime.Time) gotime.Time {
+	if t.relative {
+		return t.rel.AddTo(now)
+	}
+	return t.abs
+}
+
+// MarshalText returns t in SMPP format, empty for null.
+func (t Time) MarshalText() ([]byte, error) {
+	switch {
+	case t.relative:
+		s, err := formatPeriod(t.rel)
+		return []byte(s), err
+	case t.abs.IsZero():
+		return nil, nil
+	}
+	s, err := Format(Absolute, t.abs)
+	return []byte(s), err
+}
+
+// UnmarshalText parses an SMPP time, see ParseTime.
+func (t *Time) UnmarshalText(in []byte) error {
+	v, err := ParseTime(in)
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// String returns t in SMPP format, or a description of the error.
+func (t Time) String() string {
+	b, err := t.MarshalText()
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// ParseTime parses an SMPP time field keeping its form, with the checks
+// of Codec.Parse. Empty input is null; simple YYMMDDhhmm[ss] layouts are
+// read as absolute UTC.
+func ParseTime(in []byte) (Time, error) {
+	return parseValue(in)
+}
+
+func formatPeriod(p Period) (string, error) {
+	for _, v := range []int{p.Years, p.Months, p.Days, p.Hours, p.Minutes, p.Seconds} {
+		if v < 0 || v > 99 {
+			return "", fmt.Errorf("smpp/time: relative period %+v does not fit in two digits per field", p)
+		}
+	}
+	if p.Tenths < 0 || p.Tenths > 9 {
+		return "", fmt.Errorf("smpp/time: relative period tenths %d is out of range 0-9", p.Tenths)
+	}
+	return fmt.Sprintf("%02d%02d%02d%02d%02d%02d%d00R", p.Years, p.Months, p.Days, p.Hours, p.Minutes, p.Seconds, p.Tenths), nil
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
@@ -0,0 +1,51 @@
+package time_test
+
+import (
+	"testing"
+	gotime "time"
+
+	"github.com/majiddarvishan/smpp/time"
+)
+
+func TestTimeKeepsForm(t *testing.T) {
+	for _, in := range []string{"", "000001000000000R", "990000235959000R", "020610233429120-", "020610233429000+"} {
+		v, err := time.ParseTime([]byte(in))
+		if err != nil {
+			t.Fatalf("ParseTime(%q) error %v", in, err)
+		}
+		if v.String() != in {
+			t.Errorf("ParseTime(%q).String() => %q", in, v.String())
+		}
+		if v.IsRelative() != (len(in) > 0 && in[15] == 'R') || v.IsZero() != (in == "") {
+			t.Errorf("ParseTime(%q) => wrong form", in)
+		}
+	}
+}
+
+func TestTimeResolve(t *testing.T) {
+	now := gotime.Date(2024, gotime.January, 31, 12, 0, 0, 0, gotime.UTC)
+	v := time.NewRelative(time.Period{Months: 1, Hours: 1, Seconds: 5})
+	if got := v.Resolve(now); !got.Equal(gotime.Date(2024, gotime.March, 2, 13, 0, 5, 0, gotime.UTC)) {
+		t.Errorf("Resolve() => %s", got)
+	}
+	abs := gotime.Date(2024, gotime.May, 1, 0, 0, 0, 0, gotime.UTC)
+	if got := time.NewAbsolute(abs).Resolve(now); !got.Equal(abs) {
+		t.Errorf("Resolve() => %s", got)
+	}
+	if got := (time.Time{}).Resolve(now); !got.IsZero() {
+		t.Errorf("Resolve() of null => %s", got)
+	}
+}
+
+func TestTimeMarshalErrors(t *testing.T) {
+	if _, err := time.NewRelative(time.PeriodOf(200 * 24 * gotime.Hour)).MarshalText(); err == nil {
+		t.Error("expected error for 200 days")
+	}
+	odd := gotime.Date(2024, gotime.May, 1, 0, 0, 0, 0, gotime.FixedZone("", 600))
+	if _, err := time.NewAbsolute(odd).MarshalText(); err == nil {
+		t.Error("expected error for a 10 minute offset")
+	}
+	if _, err := time.ParseTime([]byte("0000010a0000000R")); err == nil {
+		t.Error("expected error for a letter in a relative time")
+	}
+}
@@ -0,0 +1,128 @@
+package smpp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+// SpanKind tells which operation a span covers.
+type SpanKind int
+
+const (
+	// SpanSend covers sending a PDU. For a request it lasts until the
+	// response is received, carrying its status, or until the request is
+	// released with ReleaseSequenceNumber or the session closed. For a
+	// response it ends once the PDU is written.
+	SpanSend SpanKind = iota
+	// SpanReceive covers reading a PDU once its header is read, and
+	// dispatching it. For a response its parent is the request SpanSend.
+	SpanReceive
+	// SpanRequestHandler and SpanResponseHandler cover the execution of
+	// the session handlers. Their parent is the SpanReceive of the PDU, and
+	// their context is the one returned by Context.Context.
+	SpanRequestHandler
+	SpanResponseHandler
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanSend:
+		return "smpp.send"
+	case SpanReceive:
+		return "smpp.receive"
+	case SpanRequestHandler:
+		return "smpp.request_handler"
+	case SpanResponseHandler:
+		return "smpp.response_handler"
+	}
+	return "smpp.unknown"
+}
+
+// Span describes a traced operation. Status, Duration and Err are only set
+// when it ends.
+type Span struct {
+	Kind      SpanKind
+	SessionID string
+	SystemID  string
+	CommandID pdu.CommandID
+	// Seq is the sequence number, zero when SpanSend of a request starts.
+	Seq    uint32
+	Status pdu.Status
+	Start  time.Time
+	// Duration is the span latency: the round trip for a SpanSend of a
+	// request.
+	Duration time.Duration
+	Err      error
+}
+
+// Tracer receives the start and end of spans from sessions. Server
+// sessions report to the Tracer of the server's SessionConf.
+// Implementations must be safe for concurrent use and return quickly, some
+// methods are called with the session lock held.
+type Tracer interface {
+	// StartSpan is called when span starts, with the context of its parent:
+	// the context given to SendRequest, or the one of the handler for
+	// responses sent. The returned context is passed to EndSpan and to
+	// child spans.
+	StartSpan(ctx context.Context, span Span) context.Context
+	EndSpan(ctx context.Context, span Span)
+}
+
+type nopTracer struct{}
+
+func (nopTracer) StartSpan(ctx context.Context, _ Span) context.Context { return ctx }
+func (nopTracer) EndSpan(context.Context, Span)                         {}
+
+// startSpan starts a span of kind for id with parent ctx.
+func (sess *Session) startSpan(ctx context.Context, kind SpanKind, id pdu.CommandID, seq uint32) (context.Context, Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := Span{
+		Kind:      kind,
+		SessionID: sess.conf.ID,
+		SystemID:  sess.SystemID(),
+		CommandID: id,
+		Seq:       seq,
+		Start:     time.Now(),
+	}
+	return sess.conf.Tracer.StartSpan(ctx, span), span
+}
+
+// endSpan ends span started with ctx.
+func (sess *Session) endSpan(ctx context.Context, span Span, status pdu.Status, err error) {
+	span.Status, span.Err = status, err
+	span.Duration = time.Since(span.Start)
+	sess.conf.Tracer.EndSpan(ctx, span)
+}
+
+// valuesContext has the values of vals, and the deadline and cancellation
+// of the embedded context. It lets the spans of a response descend from
+// its request span while being cancelled with the session.
+type valuesContext struct {
+	context.Context
+	vals context.Context
+}
+
+func (c valuesContext) Value(key interface{}) interface{} {
+	return c.vals.Value(key)
+}
+
+var errReleased = errors.New("smpp: request released before receiving response")
+
+// receiveParent returns the parent context of the receive span of h: the
+// send span of the request it responds to, if any, cancelled with ctx.
+func (sess *Session) receiveParent(ctx context.Context, h pdu.Header) context.Context {
+	if pdu.IsRequest(h.CommandID()) {
+		return ctx
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if req, ok := sess.sent[h.Sequence()]; ok {
+		return valuesContext{ctx, req.ctx}
+	}
+	return ctx
+}
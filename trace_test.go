@@ -0,0 +1,136 @@
+package smpp
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+type spanKey struct{}
+
+type recordedSpan struct {
+	parent string
+	span   Span
+}
+
+// recordedTracer names spans by kind and command, and records them with
+// the name of their parent when they end.
+type recordedTracer struct {
+	mu    sync.Mutex
+	ended map[string]recordedSpan
+}
+
+func spanName(span Span) string {
+	return span.Kind.String() + " " + span.CommandID.String()
+}
+
+func (tr *recordedTracer) StartSpan(ctx context.Context, span Span) context.Context {
+	parent, _ := ctx.Value(spanKey{}).(string)
+	ctx = context.WithValue(ctx, spanKey{}, spanName(span))
+	return context.WithValue(ctx, tr, parent)
+}
+
+func (tr *recordedTracer) EndSpan(ctx context.Context, span Span) {
+	tr.mu.Lock()
+	tr.ended[spanName(span)] = recordedSpan{parent: ctx.Value(tr).(string), span: span}
+	tr.mu.Unlock()
+}
+
+func TestSessionTracing(t *testing.T) {
+	local, remote := net.Pipe()
+	tr := &recordedTracer{ended: make(map[string]recordedSpan)}
+	handled := make(chan struct{})
+	sess := NewSession(local, SessionConf{
+		Type:   SMSC,
+		ID:     "s1",
+		Tracer: tr,
+		RequestHandler: RequestHandlerFunc(func(ctx *Context) {
+			bind, err := ctx.BindTx()
+			if err != nil {
+				t.Errorf("BindTx() error %v", err)
+				return
+			}
+			if err := ctx.Respond(bind.Response("smsc"), pdu.StatusOK); err != nil {
+				t.Errorf("Respond() error %v", err)
+			}
+		}),
+		ResponseHandler: ResponseHandlerFunc(func(ctx *Context) { close(handled) }),
+	})
+
+	readPDU := func() []byte {
+		t.Helper()
+		buf := make([]byte, 16)
+		if _, err := io.ReadFull(remote, buf); err != nil {
+			t.Fatal(err)
+		}
+		body := make([]byte, int(buf[3])-16)
+		if _, err := io.ReadFull(remote, body); err != nil {
+			t.Fatal(err)
+		}
+		return buf
+	}
+	enc := pdu.NewEncoder(pdu.NewSequencer(1))
+	_, buf, err := enc.Encode(&pdu.BindTx{SystemID: "esme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	readPDU()
+
+	// Request sent by the session, answered by the peer.
+	go func() {
+		hdr := readPDU()
+		seq := uint32(hdr[12])<<24 | uint32(hdr[13])<<16 | uint32(hdr[14])<<8 | uint32(hdr[15])
+		_, buf, err := enc.Encode(pdu.EnquireLinkResp{}, pdu.EncodeSeq(seq))
+		if err == nil {
+			_, err = remote.Write(buf)
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	ctx := context.WithValue(context.Background(), spanKey{}, "ingress")
+	seq, err := sess.SendRequest(ctx, pdu.EnquireLink{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("response not handled")
+	}
+	// Close waits for the handlers, and so for their spans to end.
+	sess.Close()
+
+	want := map[string]string{
+		"smpp.receive BindTransmitterID":          "",
+		"smpp.request_handler BindTransmitterID":  "smpp.receive BindTransmitterID",
+		"smpp.send BindTransmitterRespID":         "smpp.request_handler BindTransmitterID",
+		"smpp.send EnquireLinkID":                 "ingress",
+		"smpp.receive EnquireLinkRespID":          "smpp.send EnquireLinkID",
+		"smpp.response_handler EnquireLinkRespID": "smpp.receive EnquireLinkRespID",
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for name, parent := range want {
+		got, ok := tr.ended[name]
+		if !ok {
+			t.Errorf("span %s did not end", name)
+			continue
+		}
+		if got.parent != parent {
+			t.Errorf("span %s parent %q, expected %q", name, got.parent, parent)
+		}
+	}
+	send := tr.ended["smpp.send EnquireLinkID"].span
+	if send.SessionID != "s1" || send.SystemID != "esme" || send.Seq != seq || send.Status != pdu.StatusOK || send.Err != nil || send.Duration <= 0 {
+		t.Errorf("send span %+v", send)
+	}
+}
@@ -0,0 +1,121 @@
+package utility
+
+import (
+	"errors"
+)
+
+// Analysis describes how a text is encoded and split before sending.
+type Analysis struct {
+	Coding DataCoding
+	// Locking and Single are the GSM7 shift tables used.
+	Locking NationalLanguage
+	Single  NationalLanguage
+	// Length is the encoded length in septets for GSM7, octets otherwise.
+	Length int
+	// Parts holds the characters each part carries. Text sent in
+	// message_payload is a single part.
+	Parts []PartRange
+	// NonGSM7 lists, in order of first use, the characters none of the
+	// GSM7 alphabets tried can represent.
+	NonGSM7 []rune
+}
+
+// PartRange is the half-open range [Start, End) of character (rune)
+// offsets in the text.
+type PartRange struct {
+	Start, End int
+}
+
+// Analyze reports how Split and SplitWithUDH encode and split text.
+func Analyze(text string, langs ...NationalLanguage) (Analysis, error) {
+	return (&Splitter{Languages: langs}).Analyze(text)
+}
+
+// Analyze reports how sp.Split and sp.SplitWithUDH encode and split text.
+func (sp *Splitter) Analyze(text string) (Analysis, error) {
+	return sp.builder().Analyze(text)
+}
+
+// Analyze reports how Build encodes and splits text.
+func (b *SubmitSmBuilder) Analyze(text string) (Analysis, error) {
+	if len(text) == 0 {
+		return Analysis{}, errors.New("empty message")
+	}
+	enc := b.encode(text)
+	chunks, _, err := b.split(enc, enc.shift.udhIEs())
+	if err != nil {
+		return Analysis{}, err
+	}
+	a := Analysis{
+		Coding:  enc.coding,
+		Locking: enc.shift.locking,
+		Single:  enc.shift.single,
+		Length:  len(enc.data),
+		NonGSM7: nonGSM7(text, b.Languages),
+	}
+
+	// Chunks end on character boundaries, so walking the characters with
+	// their encoded sizes finds where each part starts.
+	cs, _ := charsetFor(enc.shift.locking, enc.shift.single)
+	runes := []rune(text)
+	start, pos := 0, 0
+	for _, chunk := range chunks {
+		size := 0
+		for pos < len(runes) && size < len(chunk) {
+			size += enc.size(cs, runes[pos])
+			pos++
+		}
+		a.Parts = append(a.Parts, PartRange{start, pos})
+		start = pos
+	}
+	return a, nil
+}
+
+// size returns the encoded size of r in the units chunk lengths use.
+func (enc *encodedText) size(cs *gsm7Charset, r rune) int {
+	switch enc.coding {
+	case DataCodingGSM7:
+		if code, ok := cs.lockEnc[r]; ok && code != gsm7Escape {
+			return 1
+		}
+		return 2
+	case DataCodingUCS2:
+		if r > 0xFFFF {
+			return 4
+		}
+		return 2
+	}
+	return 1
+}
+
+// nonGSM7 returns the characters of text that neither the default alphabet
+// nor the shift tables of langs can represent.
+func nonGSM7(text string, langs []NationalLanguage) []rune {
+	charsets := []*gsm7Charset{gsm7DefaultCharset}
+	for _, l := range langs {
+		for _, lock := range []NationalLanguage{LanguageDefault, l} {
+			if cs, err := charsetFor(lock, l); err == nil {
+				charsets = append(charsets, cs)
+			}
+		}
+	}
+	var out []rune
+	seen := make(map[rune]bool)
+	for _, r := range text {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		found := false
+		for _, cs := range charsets {
+			if _, err := cs.encode(string(r), GSM7Strict); err == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze_GSM7(t *testing.T) {
+	text := strings.Repeat("a", 152) + "€" + strings.Repeat("b", 10)
+	a, err := Analyze(text)
+	require.NoError(t, err)
+	require.Equal(t, DataCodingGSM7, a.Coding)
+	require.Equal(t, 164, a.Length)
+	// The escape of '€' would be the 153rd septet, so '€' moves to part 2.
+	require.Equal(t, []PartRange{{0, 152}, {152, 163}}, a.Parts)
+	require.Empty(t, a.NonGSM7)
+}
+
+func TestAnalyze_UCS2(t *testing.T) {
+	text := "Cześć, " + strings.Repeat("x", 59) + "😀😀 łódź"
+	a, err := Analyze(text)
+	require.NoError(t, err)
+	require.Equal(t, DataCodingUCS2, a.Coding)
+	require.Equal(t, 2*len([]rune(text))+4, a.Length)
+	require.Equal(t, []rune("ść😀łóź"), a.NonGSM7)
+
+	runes := []rune(text)
+	require.Len(t, a.Parts, 2)
+	require.Equal(t, PartRange{0, 66}, a.Parts[0], "surrogate pair kept in part 2")
+	require.Equal(t, PartRange{66, len(runes)}, a.Parts[1])
+}
+
+func TestAnalyze_Options(t *testing.T) {
+	a, err := Analyze("Şimdi", LanguageTurkish)
+	require.NoError(t, err)
+	require.Equal(t, DataCodingGSM7, a.Coding)
+	require.Equal(t, LanguageTurkish, a.Locking)
+	require.Empty(t, a.NonGSM7)
+
+	a, err = (&SubmitSmBuilder{Latin1: true}).Analyze("Ñandú")
+	require.NoError(t, err)
+	require.Equal(t, DataCodingLatin1, a.Coding)
+	require.Equal(t, []rune("ú"), a.NonGSM7)
+
+	a, err = (&SubmitSmBuilder{Concatenation: ConcatPayload}).Analyze(strings.Repeat("x", 500))
+	require.NoError(t, err)
+	require.Equal(t, []PartRange{{0, 500}}, a.Parts)
+
+	_, err = Analyze("")
+	require.Error(t, err)
+}
+
+func TestAnalyze_MatchesSplit(t *testing.T) {
+	sp := &Splitter{Languages: []NationalLanguage{LanguageTurkish}, Latin1: true}
+	for _, text := range []string{
+		"short",
+		strings.Repeat("a", 152) + "€" + strings.Repeat("b", 10),
+		strings.Repeat("Şimdi ığdır ", 40),
+		strings.Repeat("Ñandú ô ", 40),
+		strings.Repeat("سلام 😀 ", 40),
+	} {
+		a, err := sp.Analyze(text)
+		require.NoError(t, err)
+		res, err := sp.SplitWithUDH("", text)
+		require.NoError(t, err)
+		require.Equal(t, res.Coding, a.Coding)
+		require.Len(t, a.Parts, len(res.Bodies))
+
+		n := 0
+		for _, body := range res.Bodies {
+			n += len(body)
+		}
+		require.Equal(t, a.Length, n)
+	}
+}
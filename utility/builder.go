@@ -0,0 +1,241 @@
+package utility
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf16"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+// Concatenation selects how the parts of a long message are linked.
+type Concatenation int
+
+const (
+	// ConcatUDH8 links parts with a UDH element 0x00 (8-bit reference).
+	ConcatUDH8 Concatenation = iota
+	// ConcatUDH16 links parts with a UDH element 0x08 (16-bit reference).
+	ConcatUDH16
+	// ConcatSAR links parts with the sar_msg_ref_num, sar_total_segments
+	// and sar_segment_seqnum TLVs.
+	ConcatSAR
+	// ConcatPayload sends a single PDU carrying the text in message_payload.
+	ConcatPayload
+)
+
+// SubmitSmBuilder turns text into ready-to-send submit_sm PDUs.
+type SubmitSmBuilder struct {
+	// Concatenation links the parts of text that does not fit in one
+	// short_message. Text that fits is never concatenated.
+	Concatenation Concatenation
+	// Languages are national language shift tables to try before giving
+	// up on GSM7.
+	Languages []NationalLanguage
+	// Latin1 allows data_coding 0x03 for text GSM7 cannot represent.
+	Latin1 bool
+	// Graphemes keeps grapheme clusters within one part in UCS2.
+	Graphemes bool
+	// Refs allocates concatenation references per destination. Defaults
+	// to DefaultRefAllocator.
+	Refs RefAllocator
+	// BinaryDataCoding is the data_coding of BuildBinary PDUs. Defaults
+	// to DataCodingBinary.
+	BinaryDataCoding DataCoding
+}
+
+// Build encodes text and returns the PDUs carrying it. Each PDU is a copy
+// of tmpl with data_coding, esm_class UDHI, short_message and, depending on
+// the concatenation, SAR or message_payload options set. GSM7 text is
+// carried unpacked, one septet per octet.
+func (b *SubmitSmBuilder) Build(tmpl *pdu.SubmitSm, text string) ([]*pdu.SubmitSm, error) {
+	if len(text) == 0 {
+		return nil, errors.New("empty message")
+	}
+	enc := b.encode(text)
+	return b.build(tmpl, enc, enc.shift.udhIEs())
+}
+
+// BuildBinary returns the PDUs carrying data, such as a WAP push or an OTA
+// configuration, addressed to application ports with a 16-bit port UDH.
+// The PDUs are set up like those of Build, with data_coding set to
+// b.BinaryDataCoding.
+func (b *SubmitSmBuilder) BuildBinary(tmpl *pdu.SubmitSm, data []byte, dstPort, srcPort uint16) ([]*pdu.SubmitSm, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty message")
+	}
+	coding := b.BinaryDataCoding
+	if coding == 0 {
+		coding = DataCodingBinary
+	}
+	udh := NewUserDataHeader()
+	udh.SetPorts16(dstPort, srcPort)
+	return b.build(tmpl, &encodedText{coding: coding, data: data}, udh.Pack()[1:])
+}
+
+// build returns the PDUs carrying enc, with ies in the UDH of every part.
+func (b *SubmitSmBuilder) build(tmpl *pdu.SubmitSm, enc *encodedText, ies []byte) ([]*pdu.SubmitSm, error) {
+	chunks, payload, err := b.split(enc, ies)
+	if err != nil {
+		return nil, err
+	}
+	if payload {
+		p := enc.part(tmpl, ies, nil)
+		p.ShortMessage = ""
+		p.Options.SetMessagePayload(string(userData(ies, enc.data)))
+		return []*pdu.SubmitSm{p}, nil
+	}
+	if len(chunks) == 1 {
+		return []*pdu.SubmitSm{enc.part(tmpl, ies, chunks[0])}, nil
+	}
+
+	refs := b.Refs
+	if refs == nil {
+		refs = DefaultRefAllocator
+	}
+	bits := 16
+	if b.Concatenation == ConcatUDH8 {
+		bits = 8
+	}
+	ref := refs.Next(tmpl.DestinationAddr, bits)
+	parts := make([]*pdu.SubmitSm, len(chunks))
+	for i, chunk := range chunks {
+		total, seq := byte(len(chunks)), byte(i+1)
+		var partIEs []byte
+		switch b.Concatenation {
+		case ConcatUDH8:
+			partIEs = append(partIEs, IEIConcatenated8Bit, 0x03, byte(ref), total, seq)
+		case ConcatUDH16:
+			partIEs = append(partIEs, IEIConcatenated16Bit, 0x04, byte(ref>>8), byte(ref), total, seq)
+		}
+		partIEs = append(partIEs, ies...)
+		parts[i] = enc.part(tmpl, partIEs, chunk)
+		if b.Concatenation == ConcatSAR {
+			parts[i].Options.
+				SetSarMsgRefNum(int(ref)).
+				SetSarTotalSegments(int(total)).
+				SetSarSegmentSeqnum(int(seq))
+		}
+	}
+	return parts, nil
+}
+
+// split returns the chunks enc is sent in, one per part, or the whole of
+// enc.data as a single chunk to be carried in message_payload.
+func (b *SubmitSmBuilder) split(enc *encodedText, ies []byte) (chunks [][]byte, payload bool, err error) {
+	if chunks := enc.chunk(140 - udhLen(len(ies))); len(chunks) == 1 {
+		return chunks, false, nil
+	}
+	var concatLen int
+	switch b.Concatenation {
+	case ConcatPayload:
+		return [][]byte{enc.data}, true, nil
+	case ConcatSAR:
+	case ConcatUDH8:
+		concatLen = 5
+	case ConcatUDH16:
+		concatLen = 6
+	default:
+		return nil, false, fmt.Errorf("unknown concatenation %d", b.Concatenation)
+	}
+	chunks = enc.chunk(140 - udhLen(len(ies)+concatLen))
+	if len(chunks) > 255 {
+		return nil, false, fmt.Errorf("message needs %d parts, at most 255 allowed", len(chunks))
+	}
+	return chunks, false, nil
+}
+
+// encodedText is text encoded in the data coding chosen for it.
+type encodedText struct {
+	coding    DataCoding
+	shift     gsm7Shift
+	data      []byte // septets, Latin-1 octets or UTF-16BE
+	runes     []rune
+	graphemes bool
+}
+
+func (b *SubmitSmBuilder) encode(text string) *encodedText {
+	if shift, septets, ok := selectShift(text, b.Languages); ok {
+		return &encodedText{coding: DataCodingGSM7, shift: shift, data: septets}
+	}
+	runes := []rune(text)
+	if b.Latin1 && isLatin1(text) {
+		data := make([]byte, len(runes))
+		for i, r := range runes {
+			data[i] = byte(r)
+		}
+		return &encodedText{coding: DataCodingLatin1, data: data}
+	}
+	return &encodedText{
+		coding:    DataCodingUCS2,
+		data:      EncodeUCS2(text),
+		runes:     runes,
+		graphemes: b.Graphemes,
+	}
+}
+
+// chunk splits the encoded text into parts of at most max octets once
+// packed for the air interface.
+func (enc *encodedText) chunk(max int) [][]byte {
+	switch enc.coding {
+	case DataCodingGSM7:
+		return chunkSeptets(enc.data, max*8/7)
+	case DataCodingUCS2:
+		var units [][]uint16
+		if enc.graphemes {
+			units = chunkUTF16Graphemes(enc.runes, max/2)
+		} else {
+			units = chunkUTF16(utf16.Encode(enc.runes), max/2)
+		}
+		out := make([][]byte, len(units))
+		for i, u := range units {
+			out[i] = encodeUTF16BE(u)
+		}
+		return out
+	}
+	var out [][]byte
+	for i := 0; i < len(enc.data); i += max {
+		end := i + max
+		if end > len(enc.data) {
+			end = len(enc.data)
+		}
+		out = append(out, enc.data[i:end])
+	}
+	return out
+}
+
+// part copies tmpl into a PDU carrying body behind a UDH made of ies.
+func (enc *encodedText) part(tmpl *pdu.SubmitSm, ies, body []byte) *pdu.SubmitSm {
+	p := *tmpl
+	p.DataCoding = int(enc.coding)
+	p.ShortMessage = string(userData(ies, body))
+	if tmpl.Options != nil {
+		p.Options = tmpl.Options.Clone()
+	} else {
+		p.Options = pdu.NewOptions()
+	}
+	p.EsmClass.Feature &^= pdu.UDHIEsmFeat
+	if len(ies) > 0 {
+		p.EsmClass.Feature |= pdu.UDHIEsmFeat
+	}
+	return &p
+}
+
+// userData prefixes body with a UDH made of ies, if any.
+func userData(ies, body []byte) []byte {
+	if len(ies) == 0 {
+		return body
+	}
+	out := make([]byte, 0, 1+len(ies)+len(body))
+	out = append(out, byte(len(ies)))
+	out = append(out, ies...)
+	return append(out, body...)
+}
+
+// udhLen returns the length of a UDH, UDHL included, holding n octets of
+// information elements.
+func udhLen(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return n + 1
+}
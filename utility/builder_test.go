@@ -0,0 +1,158 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/majiddarvishan/smpp/pdu"
+	"github.com/stretchr/testify/require"
+)
+
+func template() *pdu.SubmitSm {
+	return &pdu.SubmitSm{
+		SourceAddr:         "111",
+		DestinationAddr:    "222",
+		EsmClass:           pdu.EsmClass{Feature: pdu.RepPathEsmFeat},
+		RegisteredDelivery: pdu.RegisteredYesDeliveryReceipt(),
+		Options:            pdu.NewOptions().SetUserMessageReference(7),
+	}
+}
+
+func TestBuildSubmitSm_SinglePart(t *testing.T) {
+	tmpl := template()
+	b := &SubmitSmBuilder{}
+	parts, err := b.Build(tmpl, "Café")
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	p := parts[0]
+	require.Equal(t, int(DataCodingGSM7), p.DataCoding)
+	require.Equal(t, string([]byte{'C', 'a', 'f', 0x05}), p.ShortMessage)
+	require.Equal(t, pdu.RepPathEsmFeat, p.EsmClass.Feature)
+	require.Equal(t, "222", p.DestinationAddr)
+	require.Equal(t, 7, p.Options.UserMessageReference())
+
+	p.Options.SetUserMessageReference(8)
+	require.Equal(t, 7, tmpl.Options.UserMessageReference(), "template options shared")
+	require.Empty(t, tmpl.ShortMessage)
+
+	_, err = b.Build(tmpl, "")
+	require.Error(t, err)
+}
+
+func TestBuildSubmitSm_UDH(t *testing.T) {
+	text := strings.Repeat("0123456789", 20)
+	for _, tt := range []struct {
+		concat  Concatenation
+		udh     int
+		perPart int
+	}{
+		{ConcatUDH8, 6, 153},
+		{ConcatUDH16, 7, 152},
+	} {
+		parts, err := (&SubmitSmBuilder{Concatenation: tt.concat}).Build(template(), text)
+		require.NoError(t, err)
+		require.Len(t, parts, 2)
+		var got string
+		for i, p := range parts {
+			require.Equal(t, pdu.UDHIRepPathEsmFeat, p.EsmClass.Feature)
+			udh, body, err := pdu.SeparateUDH([]byte(p.ShortMessage))
+			require.NoError(t, err)
+			require.Len(t, udh, tt.udh)
+			require.Equal(t, []byte{2, byte(i + 1)}, udh[len(udh)-2:])
+			if i == 0 {
+				require.Len(t, body, tt.perPart)
+			}
+			s, err := DecodeGSM7(body, GSM7Strict)
+			require.NoError(t, err)
+			got += s
+		}
+		require.Equal(t, text, got)
+		require.Equal(t, parts[0].ShortMessage[:tt.udh-2], parts[1].ShortMessage[:tt.udh-2], "reference differs")
+	}
+}
+
+func TestBuildSubmitSm_SAR(t *testing.T) {
+	text := strings.Repeat("سلام ", 30)
+	parts, err := (&SubmitSmBuilder{Concatenation: ConcatSAR}).Build(template(), text)
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+	var got string
+	for i, p := range parts {
+		require.Equal(t, int(DataCodingUCS2), p.DataCoding)
+		require.Equal(t, pdu.RepPathEsmFeat, p.EsmClass.Feature)
+		require.True(t, len(p.ShortMessage) <= 140)
+		require.Equal(t, parts[0].Options.SarMsgRefNum(), p.Options.SarMsgRefNum())
+		require.Equal(t, 3, p.Options.SarTotalSegments())
+		require.Equal(t, i+1, p.Options.SarSegmentSeqnum())
+		s, err := DecodeUCS2([]byte(p.ShortMessage))
+		require.NoError(t, err)
+		got += s
+	}
+	require.Equal(t, text, got)
+}
+
+func TestBuildSubmitSm_Payload(t *testing.T) {
+	text := strings.Repeat("Ñandú ", 40)
+	parts, err := (&SubmitSmBuilder{Concatenation: ConcatPayload, Latin1: true}).Build(template(), text)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	p := parts[0]
+	require.Equal(t, int(DataCodingLatin1), p.DataCoding)
+	require.Empty(t, p.ShortMessage)
+	require.Len(t, p.Options.MessagePayload(), 240)
+
+	_, err = p.MarshalBinary()
+	require.NoError(t, err)
+}
+
+func TestBuildSubmitSm_NationalLanguage(t *testing.T) {
+	parts, err := (&SubmitSmBuilder{Languages: []NationalLanguage{LanguageTurkish}}).Build(template(), "Çok güzel ş")
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	p := parts[0]
+	require.Equal(t, pdu.UDHIRepPathEsmFeat, p.EsmClass.Feature)
+	udh, body, err := pdu.SeparateUDH([]byte(p.ShortMessage))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x03, 0x25, 0x01, 0x01}, udh)
+	s, err := DecodeGSM7Language(body, LanguageTurkish, LanguageDefault, GSM7Strict)
+	require.NoError(t, err)
+	require.Equal(t, "Çok güzel ş", s)
+}
+
+func TestBuildBinary(t *testing.T) {
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	b := &SubmitSmBuilder{Concatenation: ConcatUDH8, BinaryDataCoding: DataCodingBinaryClass1}
+	parts, err := b.BuildBinary(template(), data, 2948, 9200)
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+	var got []byte
+	for i, p := range parts {
+		require.Equal(t, int(DataCodingBinaryClass1), p.DataCoding)
+		require.Equal(t, pdu.UDHIRepPathEsmFeat, p.EsmClass.Feature)
+		require.True(t, len(p.ShortMessage) <= 140)
+		udh, body, err := ParseUserDataHeader([]byte(p.ShortMessage))
+		require.NoError(t, err)
+		dst, src, ok := udh.Ports()
+		require.True(t, ok)
+		require.Equal(t, uint16(2948), dst)
+		require.Equal(t, uint16(9200), src)
+		mpd, ok := udh.MultiPart()
+		require.True(t, ok)
+		require.Equal(t, byte(3), mpd.Total)
+		require.Equal(t, byte(i+1), mpd.Seq)
+		if i == 0 {
+			require.Len(t, body, 128)
+		}
+		got = append(got, body...)
+	}
+	require.Equal(t, data, got)
+
+	parts, err = (&SubmitSmBuilder{}).BuildBinary(template(), data[:128], 2948, 9200)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	require.Equal(t, int(DataCodingBinary), parts[0].DataCoding)
+	require.Equal(t, []byte{0x06, IEIPort16Bit, 0x04, 0x0B, 0x84, 0x23, 0xF0}, []byte(parts[0].ShortMessage[:7]))
+}
@@ -22,6 +22,6 @@ var gsm7Default = map[rune]byte{
 
 // GSM 03.38 extension table
 var gsm7Ext = map[rune]byte{
-	'^': 0x14, '{': 0x28, '}': 0x29, '\\': 0x2F, '[': 0x3C,
+	'\f': 0x0A, '^': 0x14, '{': 0x28, '}': 0x29, '\\': 0x2F, '[': 0x3C,
 	'~': 0x3D, ']': 0x3E, '|': 0x40, '€': 0x65,
 }
\ No newline at end of file
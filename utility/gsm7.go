@@ -0,0 +1,160 @@
+package utility
+
+import (
+	"fmt"
+)
+
+// GSM7Mode selects how characters without a GSM 03.38 mapping are handled.
+type GSM7Mode int
+
+const (
+	// GSM7Lossy replaces unmappable characters instead of failing. On
+	// encode they become '?', on decode unknown escape sequences fall
+	// back to the default alphabet character as 3GPP 23.038 suggests.
+	GSM7Lossy GSM7Mode = iota
+	// GSM7Strict returns an error on the first unmappable character.
+	GSM7Strict
+)
+
+const gsm7Escape byte = 0x1B
+
+// gsm7Charset is a locking shift table paired with a single shift
+// (extension) table.
+type gsm7Charset struct {
+	lockEnc map[rune]byte
+	lockDec [128]rune
+	extEnc  map[rune]byte
+	extDec  map[byte]rune
+}
+
+var gsm7DefaultCharset = &gsm7Charset{
+	lockEnc: gsm7Default,
+	extEnc:  gsm7Ext,
+	extDec:  make(map[byte]rune, len(gsm7Ext)),
+}
+
+func init() {
+	for r, c := range gsm7Default {
+		gsm7DefaultCharset.lockDec[c] = r
+	}
+	for r, c := range gsm7Ext {
+		gsm7DefaultCharset.extDec[c] = r
+	}
+}
+
+// EncodeGSM7 converts text to GSM 03.38 septets, one septet per octet,
+// as SMPP short_message usually carries them. Extension characters are
+// written as an escape followed by their extension code.
+func EncodeGSM7(text string, mode GSM7Mode) ([]byte, error) {
+	return gsm7DefaultCharset.encode(text, mode)
+}
+
+// DecodeGSM7 converts unpacked GSM 03.38 septets back to text.
+func DecodeGSM7(septets []byte, mode GSM7Mode) (string, error) {
+	return gsm7DefaultCharset.decode(septets, mode)
+}
+
+// EncodeGSM7Packed converts text to GSM 03.38 septets packed into octets.
+// It returns the packed data and the number of septets of text. When the
+// last octet has 7 spare bits they are filled with CR, so they do not read
+// as '@'.
+func EncodeGSM7Packed(text string, mode GSM7Mode) ([]byte, int, error) {
+	septets, err := EncodeGSM7(text, mode)
+	if err != nil {
+		return nil, 0, err
+	}
+	n := len(septets)
+	if n%8 == 7 {
+		septets = append(septets, '\r')
+	}
+	return packSeptets(septets), n, nil
+}
+
+// DecodeGSM7Packed unpacks n septets from packed octets and converts them
+// to text. If n is negative the count is derived from the data length and
+// a trailing CR filling the last 7 spare bits is dropped.
+func DecodeGSM7Packed(octets []byte, n int, mode GSM7Mode) (string, error) {
+	max := len(octets) * 8 / 7
+	if n > max {
+		return "", fmt.Errorf("gsm7: %d septets do not fit in %d octets", n, len(octets))
+	}
+	strip := n < 0
+	if strip {
+		n = max
+	}
+	septets := unpackSeptets(octets, n)
+	if strip && n > 0 && n%8 == 0 && septets[n-1] == '\r' {
+		septets = septets[:n-1]
+	}
+	return DecodeGSM7(septets, mode)
+}
+
+func (cs *gsm7Charset) encode(text string, mode GSM7Mode) ([]byte, error) {
+	septets := make([]byte, 0, len(text))
+	for i, r := range text {
+		if code, ok := cs.lockEnc[r]; ok && code != gsm7Escape {
+			septets = append(septets, code)
+		} else if ext, ok := cs.extEnc[r]; ok {
+			septets = append(septets, gsm7Escape, ext)
+		} else if mode == GSM7Strict {
+			return nil, fmt.Errorf("gsm7: character %q at offset %d has no GSM 03.38 mapping", r, i)
+		} else {
+			septets = append(septets, gsm7Default['?'])
+		}
+	}
+	return septets, nil
+}
+
+func (cs *gsm7Charset) decode(septets []byte, mode GSM7Mode) (string, error) {
+	out := make([]rune, 0, len(septets))
+	for i := 0; i < len(septets); i++ {
+		c := septets[i]
+		if c > 0x7F {
+			if mode == GSM7Strict {
+				return "", fmt.Errorf("gsm7: octet 0x%02X at offset %d is not a septet", c, i)
+			}
+			c &= 0x7F
+		}
+		if c != gsm7Escape {
+			out = append(out, cs.lockDec[c])
+			continue
+		}
+		if i+1 == len(septets) {
+			if mode == GSM7Strict {
+				return "", fmt.Errorf("gsm7: dangling escape at offset %d", i)
+			}
+			out = append(out, ' ')
+			break
+		}
+		i++
+		ext := septets[i] & 0x7F
+		if r, ok := cs.extDec[ext]; ok {
+			out = append(out, r)
+			continue
+		}
+		if mode == GSM7Strict {
+			return "", fmt.Errorf("gsm7: unknown extension code 0x%02X at offset %d", ext, i)
+		}
+		if ext == gsm7Escape {
+			out = append(out, ' ')
+		} else {
+			out = append(out, cs.lockDec[ext])
+		}
+	}
+	return string(out), nil
+}
+
+// unpackSeptets extracts n septets from packed octets.
+func unpackSeptets(octets []byte, n int) []byte {
+	septets := make([]byte, n)
+	for i := range septets {
+		bitPos := uint(i * 7)
+		bytePos, offset := bitPos/8, bitPos%8
+		s := octets[bytePos] >> offset
+		if offset > 1 {
+			s |= octets[bytePos+1] << (8 - offset)
+		}
+		septets[i] = s & 0x7F
+	}
+	return septets
+}
@@ -0,0 +1,267 @@
+package utility
+
+import (
+	"fmt"
+)
+
+// NationalLanguage identifies a 3GPP 23.038 national language shift table.
+type NationalLanguage byte
+
+// Supported national languages, valued as their 3GPP 23.038 identifiers.
+const (
+	LanguageDefault    NationalLanguage = 0x00 // GSM 03.38 default alphabet
+	LanguageTurkish    NationalLanguage = 0x01
+	LanguageSpanish    NationalLanguage = 0x02
+	LanguagePortuguese NationalLanguage = 0x03
+	LanguageHindi      NationalLanguage = 0x06
+)
+
+// nationalLockingShift holds the locking shift tables indexed by septet.
+// Spanish only defines a single shift table.
+var nationalLockingShift = map[NationalLanguage]string{
+	LanguageTurkish: "@£$¥€éùıòÇ\nĞğ\rÅåΔ_ΦΓΛΩΠΨΣΘΞ\x1bŞşßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"İABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§çabcdefghijklmnopqrstuvwxyzäöñüà",
+	LanguagePortuguese: "@£$¥êéúíóç\nÔô\rÁáΔ_ªÇÀ∞^\\€Ó|\x1bÂâÊÉ !\"#º%&'()*+,-./0123456789:;<=>?" +
+		"ÍABCDEFGHIJKLMNOPQRSTUVWXYZÃÕÚÜ§~abcdefghijklmnopqrstuvwxyzãõ`üà",
+	LanguageHindi: "ँंःअआइईउऊऋ\nऌऍ\rऎए" +
+		"ऐऑऒओऔकखगघङच\x1bछजझञ" +
+		" !टठडढणत)(थद,ध.न" +
+		"0123456789:;ऩपफ?" +
+		"बभमयरऱलळऴवशषसह़ऽ" +
+		"ािीुूृॄॅॆेैॉॊोौ्" +
+		"ॐabcdefghijklmnopqrstuvwxyzॲॻॼॾॿ",
+}
+
+// nationalSingleShift holds the sparse single shift tables.
+var nationalSingleShift = map[NationalLanguage]map[byte]rune{
+	LanguageTurkish: {
+		0x0A: '\f', 0x14: '^', 0x28: '{', 0x29: '}', 0x2F: '\\', 0x3C: '[', 0x3D: '~', 0x3E: ']',
+		0x40: '|', 0x47: 'Ğ', 0x49: 'İ', 0x53: 'Ş', 0x63: 'ç', 0x65: '€', 0x67: 'ğ', 0x69: 'ı',
+		0x73: 'ş',
+	},
+	LanguageSpanish: {
+		0x09: 'ç', 0x0A: '\f', 0x14: '^', 0x28: '{', 0x29: '}', 0x2F: '\\', 0x3C: '[', 0x3D: '~',
+		0x3E: ']', 0x40: '|', 0x41: 'Á', 0x49: 'Í', 0x4F: 'Ó', 0x55: 'Ú', 0x61: 'á', 0x65: '€',
+		0x69: 'í', 0x6F: 'ó', 0x75: 'ú',
+	},
+	LanguagePortuguese: {
+		0x05: 'ê', 0x09: 'ç', 0x0A: '\f', 0x0B: 'Ô', 0x0C: 'ô', 0x0E: 'Á', 0x0F: 'á', 0x12: 'Φ',
+		0x13: 'Γ', 0x14: '^', 0x15: 'Ω', 0x16: 'Π', 0x17: 'Ψ', 0x18: 'Σ', 0x19: 'Θ', 0x1F: 'Ê',
+		0x28: '{', 0x29: '}', 0x2F: '\\', 0x3C: '[', 0x3D: '~', 0x3E: ']', 0x40: '|', 0x41: 'À',
+		0x49: 'Í', 0x4F: 'Ó', 0x55: 'Ú', 0x5B: 'Ã', 0x5C: 'Õ', 0x61: 'Â', 0x65: '€', 0x69: 'í',
+		0x6F: 'ó', 0x75: 'ú', 0x7B: 'ã', 0x7C: 'õ', 0x7F: 'â',
+	},
+	LanguageHindi: {
+		0x00: '@', 0x01: '£', 0x02: '$', 0x03: '¥', 0x04: '¿', 0x05: '"', 0x06: '¤', 0x07: '%',
+		0x08: '&', 0x09: '\'', 0x0A: '\f', 0x0B: '*', 0x0C: '+', 0x0D: '\r', 0x0E: '-', 0x0F: '/',
+		0x10: '<', 0x11: '=', 0x12: '>', 0x13: '¡', 0x14: '^', 0x15: '¡', 0x16: '_', 0x17: '#',
+		0x18: '*', 0x19: '\u0964', 0x1A: '\u0965', 0x1C: '\u0966', 0x1D: '\u0967', 0x1E: '\u0968',
+		0x1F: '\u0969', 0x20: '\u096A', 0x21: '\u096B', 0x22: '\u096C', 0x23: '\u096D',
+		0x24: '\u096E', 0x25: '\u096F', 0x26: '\u0951', 0x27: '\u0952', 0x28: '{', 0x29: '}',
+		0x2A: '\u0953', 0x2B: '\u0954', 0x2C: '\u0958', 0x2D: '\u0959', 0x2E: '\u095A', 0x2F: '\\',
+		0x30: '\u095B', 0x31: '\u095C', 0x32: '\u095D', 0x33: '\u095E', 0x34: '\u095F',
+		0x35: '\u0960', 0x36: '\u0961', 0x37: '\u0962', 0x38: '\u0963', 0x39: '\u0970',
+		0x3A: '\u0971', 0x3C: '[', 0x3D: '~', 0x3E: ']', 0x40: '|', 0x41: 'A', 0x42: 'B',
+		0x43: 'C', 0x44: 'D', 0x45: 'E', 0x46: 'F', 0x47: 'G', 0x48: 'H', 0x49: 'I', 0x4A: 'J',
+		0x4B: 'K', 0x4C: 'L', 0x4D: 'M', 0x4E: 'N', 0x4F: 'O', 0x50: 'P', 0x51: 'Q', 0x52: 'R',
+		0x53: 'S', 0x54: 'T', 0x55: 'U', 0x56: 'V', 0x57: 'W', 0x58: 'X', 0x59: 'Y', 0x5A: 'Z',
+		0x65: '€',
+	},
+}
+
+// gsm7Charsets caches every supported locking/single shift combination.
+var gsm7Charsets = make(map[[2]NationalLanguage]*gsm7Charset)
+
+func init() {
+	langs := []NationalLanguage{LanguageDefault}
+	for l := range nationalSingleShift {
+		langs = append(langs, l)
+	}
+	for _, lock := range langs {
+		if _, ok := nationalLockingShift[lock]; !ok && lock != LanguageDefault {
+			continue
+		}
+		for _, single := range langs {
+			gsm7Charsets[[2]NationalLanguage{lock, single}] = newGSM7Charset(lock, single)
+		}
+	}
+}
+
+func newGSM7Charset(lock, single NationalLanguage) *gsm7Charset {
+	if lock == LanguageDefault && single == LanguageDefault {
+		return gsm7DefaultCharset
+	}
+	cs := &gsm7Charset{
+		lockEnc: gsm7Default,
+		lockDec: gsm7DefaultCharset.lockDec,
+		extEnc:  gsm7Ext,
+		extDec:  gsm7DefaultCharset.extDec,
+	}
+	if lock != LanguageDefault {
+		table := []rune(nationalLockingShift[lock])
+		if len(table) != 128 {
+			panic(fmt.Sprintf("utility: locking shift table %d has %d entries", lock, len(table)))
+		}
+		cs.lockEnc = make(map[rune]byte, 128)
+		for c, r := range table {
+			cs.lockDec[c] = r
+			if _, ok := cs.lockEnc[r]; !ok {
+				cs.lockEnc[r] = byte(c)
+			}
+		}
+	}
+	if single != LanguageDefault {
+		cs.extEnc = make(map[rune]byte)
+		cs.extDec = nationalSingleShift[single]
+		// Lower codes win when a table lists a character twice.
+		for c := 0; c < 128; c++ {
+			r, ok := cs.extDec[byte(c)]
+			if _, dup := cs.extEnc[r]; ok && !dup {
+				cs.extEnc[r] = byte(c)
+			}
+		}
+	}
+	return cs
+}
+
+func charsetFor(lock, single NationalLanguage) (*gsm7Charset, error) {
+	cs, ok := gsm7Charsets[[2]NationalLanguage{lock, single}]
+	if !ok {
+		return nil, fmt.Errorf("gsm7: unsupported shift tables: locking %d, single %d", lock, single)
+	}
+	return cs, nil
+}
+
+// EncodeGSM7Language is like EncodeGSM7 but uses the locking and single
+// shift tables of the given national languages.
+func EncodeGSM7Language(text string, locking, single NationalLanguage, mode GSM7Mode) ([]byte, error) {
+	cs, err := charsetFor(locking, single)
+	if err != nil {
+		return nil, err
+	}
+	return cs.encode(text, mode)
+}
+
+// DecodeGSM7Language is like DecodeGSM7 but uses the locking and single
+// shift tables of the given national languages.
+func DecodeGSM7Language(septets []byte, locking, single NationalLanguage, mode GSM7Mode) (string, error) {
+	cs, err := charsetFor(locking, single)
+	if err != nil {
+		return "", err
+	}
+	return cs.decode(septets, mode)
+}
+
+// gsm7Shift is a choice of shift tables for a text.
+type gsm7Shift struct {
+	locking, single NationalLanguage
+}
+
+// ies returns the number of national language UDH elements s needs.
+func (s gsm7Shift) ies() int {
+	n := 0
+	if s.locking != LanguageDefault {
+		n++
+	}
+	if s.single != LanguageDefault {
+		n++
+	}
+	return n
+}
+
+// selectShift picks the shift tables among the default alphabet and langs
+// that encode text in the fewest parts, preferring fewer UDH elements and
+// then fewer septets. It returns the chosen tables and the encoded septets.
+func selectShift(text string, langs []NationalLanguage) (gsm7Shift, []byte, bool) {
+	cands := []NationalLanguage{LanguageDefault}
+	for _, l := range langs {
+		if _, ok := nationalSingleShift[l]; ok {
+			cands = append(cands, l)
+		}
+	}
+	var (
+		best        gsm7Shift
+		bestSeptets []byte
+		bestParts   int
+	)
+	for _, lock := range cands {
+		for _, single := range cands {
+			s := gsm7Shift{lock, single}
+			cs, err := charsetFor(lock, single)
+			if err != nil {
+				continue
+			}
+			septets, err := cs.encode(text, GSM7Strict)
+			if err != nil {
+				continue
+			}
+			parts := len(chunkGSM7(septets, s.ies()))
+			better := bestParts == 0 || parts < bestParts ||
+				parts == bestParts && s.ies() < best.ies() ||
+				parts == bestParts && s.ies() == best.ies() && len(septets) < len(bestSeptets)
+			if better {
+				best, bestSeptets, bestParts = s, septets, parts
+			}
+		}
+	}
+	return best, bestSeptets, bestParts > 0
+}
+
+// gsm7UDHLen returns the UDH length, UDHL included, of a part carrying
+// ies national language elements and optionally a concatenation element.
+func gsm7UDHLen(ies int, concat bool) int {
+	n := 3 * ies
+	if concat {
+		n += 5
+	}
+	if n > 0 {
+		n++
+	}
+	return n
+}
+
+// chunkGSM7 splits septets into parts that fit next to their UDH.
+func chunkGSM7(septets []byte, ies int) [][]byte {
+	if max := (140 - gsm7UDHLen(ies, false)) * 8 / 7; len(septets) <= max {
+		return [][]byte{septets}
+	}
+	return chunkSeptets(septets, (140-gsm7UDHLen(ies, true))*8/7)
+}
+
+// udhIEs returns the UDH elements announcing s.
+func (s gsm7Shift) udhIEs() []byte {
+	var ies []byte
+	if s.single != LanguageDefault {
+		ies = append(ies, IEINationalSingleShift, 0x01, byte(s.single))
+	}
+	if s.locking != LanguageDefault {
+		ies = append(ies, IEINationalLockingShift, 0x01, byte(s.locking))
+	}
+	return ies
+}
+
+// splitGSM7 splits septets encoded with the shift tables s into parts to
+// dst. Parts that need a UDH carry it followed by the septets, unpacked as
+// SMPP short_message carries them.
+func (sp *Splitter) splitGSM7(dst string, septets []byte, s gsm7Shift) [][]byte {
+	chunks := chunkGSM7(septets, s.ies())
+	var ref byte
+	if len(chunks) > 1 {
+		ref = sp.ref(dst)
+	}
+	parts := make([][]byte, 0, len(chunks))
+	for i, chunk := range chunks {
+		var ies []byte
+		if len(chunks) > 1 {
+			ies = append(ies, IEIConcatenated8Bit, 0x03, ref, byte(len(chunks)), byte(i+1))
+		}
+		ies = append(ies, s.udhIEs()...)
+		if len(ies) == 0 {
+			parts = append(parts, chunk)
+			continue
+		}
+		udh := append([]byte{byte(len(ies))}, ies...)
+		parts = append(parts, append(udh, chunk...))
+	}
+	return parts
+}
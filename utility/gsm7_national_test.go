@@ -0,0 +1,125 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// decodePart reverses Splitter.splitGSM7 for one part.
+func decodePart(t *testing.T, part []byte) ([]byte, string) {
+	udh := part[:1+int(part[0])]
+	var locking, single NationalLanguage
+	for ies := udh[1:]; len(ies) >= 2; ies = ies[2+int(ies[1]):] {
+		switch ies[0] {
+		case IEINationalLockingShift:
+			locking = NationalLanguage(ies[2])
+		case IEINationalSingleShift:
+			single = NationalLanguage(ies[2])
+		}
+	}
+	text, err := DecodeGSM7Language(part[len(udh):], locking, single, GSM7Strict)
+	require.NoError(t, err)
+	return udh, text
+}
+
+func TestNationalLanguageRoundTrip(t *testing.T) {
+	for lock := range gsm7Charsets {
+		cs := gsm7Charsets[lock]
+		var all []rune
+		for r, c := range cs.lockEnc {
+			if c != gsm7Escape && cs.lockDec[c] == r {
+				all = append(all, r)
+			}
+		}
+		for r := range cs.extEnc {
+			all = append(all, r)
+		}
+		text := string(all)
+		septets, err := EncodeGSM7Language(text, lock[0], lock[1], GSM7Strict)
+		require.NoError(t, err, "tables %v", lock)
+		decoded, err := DecodeGSM7Language(septets, lock[0], lock[1], GSM7Strict)
+		require.NoError(t, err)
+		require.Equal(t, text, decoded, "tables %v", lock)
+	}
+}
+
+func TestEncodeGSM7Language(t *testing.T) {
+	septets, err := EncodeGSM7Language("ğŞ", LanguageDefault, LanguageTurkish, GSM7Strict)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x1B, 0x67, 0x1B, 0x53}, septets)
+
+	septets, err = EncodeGSM7Language("ğŞ", LanguageTurkish, LanguageTurkish, GSM7Strict)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x0C, 0x1C}, septets)
+
+	_, err = EncodeGSM7Language("ğ", LanguageDefault, LanguageDefault, GSM7Strict)
+	require.Error(t, err)
+
+	_, err = EncodeGSM7Language("a", LanguageSpanish, LanguageDefault, GSM7Strict)
+	require.Error(t, err, "Spanish has no locking shift table")
+}
+
+func TestSplit_NationalSingleShift(t *testing.T) {
+	text := "Canción para él y Óscar"
+	parts, dcs, err := Split(text, LanguageSpanish)
+	require.NoError(t, err)
+	require.Equal(t, DataCodingGSM7, dcs)
+	require.Len(t, parts, 1)
+
+	udh, decoded := decodePart(t, parts[0])
+	require.Equal(t, []byte{0x03, 0x24, 0x01, 0x02}, udh)
+	require.Equal(t, text, decoded)
+}
+
+func TestSplit_NationalLockingShift(t *testing.T) {
+	text := strings.Repeat("Şimdi ığdır'dan İstanbul'a gidiyoruz, çok güzel. ", 5)
+	parts, dcs, err := Split(text, LanguageSpanish, LanguageTurkish)
+	require.NoError(t, err)
+	require.Equal(t, DataCodingGSM7, dcs)
+	require.Len(t, parts, 2)
+
+	septets, err := EncodeGSM7Language(text, LanguageTurkish, LanguageDefault, GSM7Strict)
+	require.NoError(t, err)
+	counts := []int{149, len(septets) - 149}
+
+	var got string
+	for i, p := range parts {
+		require.Len(t, p, 9+counts[i])
+		udh, decoded := decodePart(t, p)
+		require.Equal(t, []byte{0x08, 0x00, 0x03, udh[3], 0x02, byte(i + 1), 0x25, 0x01, 0x01}, udh)
+		got += decoded
+	}
+	require.Equal(t, text, got)
+}
+
+func TestSplit_NationalHindi(t *testing.T) {
+	text := "नमस्ते दुनिया"
+	parts, dcs, err := Split(text, LanguageHindi)
+	require.NoError(t, err)
+	require.Equal(t, DataCodingGSM7, dcs)
+	udh, decoded := decodePart(t, parts[0])
+	require.Equal(t, []byte{0x03, 0x25, 0x01, 0x06}, udh)
+	require.Equal(t, text, decoded)
+}
+
+func TestSplit_NationalFallsBackToUCS2(t *testing.T) {
+	_, dcs, err := Split("Şimdi 👋", LanguageTurkish)
+	require.NoError(t, err)
+	require.Equal(t, DataCodingUCS2, dcs)
+
+	_, dcs, err = Split("Şimdi")
+	require.NoError(t, err)
+	require.Equal(t, DataCodingUCS2, dcs)
+}
+
+func TestSplitWithUDH_National(t *testing.T) {
+	res, err := SplitWithUDH("Çok güzel ş", LanguageTurkish)
+	require.NoError(t, err)
+	require.Equal(t, DataCodingGSM7, res.Coding)
+	require.Len(t, res.UDHs, 1)
+	locking, single := res.UDHs[0].GetNationalLanguage()
+	require.Equal(t, LanguageTurkish, locking)
+	require.Equal(t, LanguageDefault, single)
+}
@@ -0,0 +1,121 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeGSM7(t *testing.T) {
+	septets, err := EncodeGSM7("@¡Ä{€}", GSM7Strict)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x00, 0x40, 0x5B, 0x1B, 0x28, 0x1B, 0x65, 0x1B, 0x29}, septets)
+}
+
+func TestEncodeGSM7_Unmappable(t *testing.T) {
+	_, err := EncodeGSM7("ok 👋", GSM7Strict)
+	require.Error(t, err)
+
+	septets, err := EncodeGSM7("ok 👋\x1b", GSM7Lossy)
+	require.NoError(t, err)
+	require.Equal(t, []byte{'o', 'k', ' ', '?', '?'}, septets)
+}
+
+func TestDecodeGSM7(t *testing.T) {
+	text, err := DecodeGSM7([]byte{0x00, 0x40, 0x11, 0x1B, 0x3C, 0x1B, 0x0A}, GSM7Strict)
+	require.NoError(t, err)
+	require.Equal(t, "@¡_[\f", text)
+}
+
+func TestDecodeGSM7_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    []byte
+		lossy string
+	}{
+		{"dangling escape", []byte{'a', 0x1B}, "a "},
+		{"unknown extension", []byte{0x1B, 'a'}, "a"},
+		{"double escape", []byte{0x1B, 0x1B}, " "},
+		{"eighth bit set", []byte{0xC1}, "A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeGSM7(tt.in, GSM7Strict)
+			require.Error(t, err)
+			text, err := DecodeGSM7(tt.in, GSM7Lossy)
+			require.NoError(t, err)
+			require.Equal(t, tt.lossy, text)
+		})
+	}
+}
+
+func TestGSM7PackedKnownVector(t *testing.T) {
+	// "hellohello" from 3GPP 23.038 section 6.1.2.1.1.
+	packed, n, err := EncodeGSM7Packed("hellohello", GSM7Strict)
+	require.NoError(t, err)
+	require.Equal(t, 10, n)
+	require.Equal(t, []byte{0xE8, 0x32, 0x9B, 0xFD, 0x46, 0x97, 0xD9, 0xEC, 0x37}, packed)
+
+	text, err := DecodeGSM7Packed(packed, n, GSM7Strict)
+	require.NoError(t, err)
+	require.Equal(t, "hellohello", text)
+}
+
+func TestDecodeGSM7Packed_CRPadding(t *testing.T) {
+	// Seven septets leave seven spare bits, which senders fill with CR.
+	packed := packSeptets([]byte("abcdefg\r"))
+	require.Len(t, packed, 7)
+	text, err := DecodeGSM7Packed(packed, -1, GSM7Strict)
+	require.NoError(t, err)
+	require.Equal(t, "abcdefg", text)
+
+	_, err = DecodeGSM7Packed(packed, 9, GSM7Strict)
+	require.Error(t, err)
+}
+
+func TestEncodeGSM7Packed_CRPadding(t *testing.T) {
+	for _, l := range []int{7, 15, 8*20 + 7} {
+		text := strings.Repeat("1234567", l/7+1)[:l]
+		packed, n, err := EncodeGSM7Packed(text, GSM7Strict)
+		require.NoError(t, err)
+		require.Equal(t, l, n)
+		require.Len(t, packed, (l*7+7)/8)
+		require.Equal(t, byte('\r'<<1), packed[len(packed)-1]&0xFE)
+
+		decoded, err := DecodeGSM7Packed(packed, -1, GSM7Strict)
+		require.NoError(t, err)
+		require.Equal(t, text, decoded)
+		decoded, err = DecodeGSM7Packed(packed, n, GSM7Strict)
+		require.NoError(t, err)
+		require.Equal(t, text, decoded)
+	}
+}
+
+func TestGSM7RoundTrip(t *testing.T) {
+	var all []rune
+	for r, c := range gsm7Default {
+		if c != gsm7Escape {
+			all = append(all, r)
+		}
+	}
+	for r := range gsm7Ext {
+		all = append(all, r)
+	}
+	text := string(all)
+
+	septets, err := EncodeGSM7(text, GSM7Strict)
+	require.NoError(t, err)
+	decoded, err := DecodeGSM7(septets, GSM7Strict)
+	require.NoError(t, err)
+	require.Equal(t, text, decoded)
+
+	for l := 0; l <= 17; l++ {
+		sub := string(all[:l])
+		packed, n, err := EncodeGSM7Packed(sub, GSM7Strict)
+		require.NoError(t, err)
+		decoded, err := DecodeGSM7Packed(packed, n, GSM7Strict)
+		require.NoError(t, err)
+		require.Equal(t, sub, decoded)
+	}
+}
@@ -0,0 +1,243 @@
+package utility
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+)
+
+// ErrReassemblyFull is returned by Reassembler.Add when starting a new
+// message would exceed the configured memory limits.
+var ErrReassemblyFull = errors.New("reassembly limits reached")
+
+// Message is a short message rebuilt from its parts.
+type Message struct {
+	SourceAddr      string
+	DestinationAddr string
+	DataCoding      int
+	Ref             uint16
+	Parts           int
+	Data            []byte // joined bodies, UDHs removed
+	Text            string // Data decoded, empty for binary data codings
+}
+
+// ReassemblerConf configures a Reassembler.
+type ReassemblerConf struct {
+	// Timeout after the first part an incomplete message is dropped.
+	// Defaults to 2 minutes.
+	Timeout time.Duration
+	// MaxPending limits the number of incomplete messages held. Defaults to 10000.
+	MaxPending int
+	// MaxBytes limits the bytes held by incomplete messages. Defaults to 16 MiB.
+	MaxBytes int
+	// OnTimeout is called when an incomplete message is dropped.
+	OnTimeout func(src, dst string, ref uint16, received, total int)
+}
+
+// Reassembler rebuilds concatenated messages from deliver_sm parts linked
+// by UDH (8- or 16-bit reference) or SAR TLVs. Parts may arrive in any
+// order; duplicates are ignored.
+type Reassembler struct {
+	conf    ReassemblerConf
+	mu      sync.Mutex
+	pending map[reassemblyKey]*partialMessage
+	bytes   int
+	closed  bool
+}
+
+type reassemblyKey struct {
+	src, dst string
+	ref      uint16
+}
+
+type partialMessage struct {
+	parts    [][]byte
+	received int
+	size     int
+	coding   int
+	locking  NationalLanguage
+	single   NationalLanguage
+	timer    *time.Timer
+}
+
+// NewReassembler creates a Reassembler.
+func NewReassembler(conf ReassemblerConf) *Reassembler {
+	if conf.Timeout == 0 {
+		conf.Timeout = 2 * time.Minute
+	}
+	if conf.MaxPending == 0 {
+		conf.MaxPending = 10000
+	}
+	if conf.MaxBytes == 0 {
+		conf.MaxBytes = 16 << 20
+	}
+	return &Reassembler{
+		conf:    conf,
+		pending: make(map[reassemblyKey]*partialMessage),
+	}
+}
+
+// Add feeds a part to the reassembler. It returns the message once all its
+// parts have arrived, and nil while parts are missing. Messages that are
+// not concatenated are returned right away.
+func (r *Reassembler) Add(p *pdu.DeliverSm) (*Message, error) {
+	body := []byte(p.ShortMessage)
+	if len(body) == 0 && p.Options != nil {
+		body = []byte(p.Options.MessagePayload())
+	}
+	var (
+		ref             uint16
+		total, seq      int
+		concat          bool
+		locking, single NationalLanguage
+	)
+	if p.EsmClass.Feature&pdu.UDHIEsmFeat != 0 {
+		udh, rest, err := ParseUserDataHeader(body)
+		if err != nil {
+			return nil, err
+		}
+		body = rest
+		locking, single = udh.GetNationalLanguage()
+		if mpd, ok := udh.MultiPart(); ok {
+			ref, total, seq, concat = mpd.Ref, int(mpd.Total), int(mpd.Seq), true
+		}
+	}
+	if !concat && p.Options != nil {
+		if _, ok := p.Options.Get(pdu.TagSarMsgRefNum); ok {
+			ref = uint16(p.Options.SarMsgRefNum())
+			total, seq = p.Options.SarTotalSegments(), p.Options.SarSegmentSeqnum()
+			concat = true
+		}
+	}
+
+	msg := &Message{
+		SourceAddr:      p.SourceAddr,
+		DestinationAddr: p.DestinationAddr,
+		DataCoding:      p.DataCoding,
+		Ref:             ref,
+		Parts:           1,
+	}
+	if !concat || total == 1 {
+		msg.Data = body
+		msg.Text, _ = DecodeText(p.DataCoding, body, locking, single, GSM7Lossy)
+		return msg, nil
+	}
+	if total == 0 || seq == 0 || seq > total {
+		return nil, fmt.Errorf("invalid part %d of %d", seq, total)
+	}
+
+	key := reassemblyKey{p.SourceAddr, p.DestinationAddr, ref}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, errors.New("reassembler closed")
+	}
+	pm, ok := r.pending[key]
+	if !ok {
+		if len(r.pending) >= r.conf.MaxPending || r.bytes+len(body) > r.conf.MaxBytes {
+			return nil, ErrReassemblyFull
+		}
+		pm = &partialMessage{parts: make([][]byte, total), coding: p.DataCoding}
+		pm.timer = time.AfterFunc(r.conf.Timeout, func() { r.expire(key, pm) })
+		r.pending[key] = pm
+	}
+	if len(pm.parts) != total {
+		return nil, fmt.Errorf("part %d claims %d parts, message has %d", seq, total, len(pm.parts))
+	}
+	if pm.parts[seq-1] != nil {
+		return nil, nil
+	}
+	if r.bytes+len(body) > r.conf.MaxBytes {
+		return nil, ErrReassemblyFull
+	}
+	pm.parts[seq-1] = body
+	pm.received++
+	pm.size += len(body)
+	r.bytes += len(body)
+	if locking != LanguageDefault || single != LanguageDefault {
+		pm.locking, pm.single = locking, single
+	}
+	if seq == 1 {
+		pm.coding = p.DataCoding
+	}
+	if pm.received < total {
+		return nil, nil
+	}
+
+	r.remove(key, pm)
+	msg.DataCoding = pm.coding
+	msg.Parts = total
+	msg.Data = make([]byte, 0, pm.size)
+	for _, part := range pm.parts {
+		msg.Data = append(msg.Data, part...)
+	}
+	msg.Text, _ = DecodeText(pm.coding, msg.Data, pm.locking, pm.single, GSM7Lossy)
+	return msg, nil
+}
+
+// Pending returns the number of incomplete messages held.
+func (r *Reassembler) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
+
+// Close drops all incomplete messages without calling OnTimeout.
+func (r *Reassembler) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, pm := range r.pending {
+		r.remove(key, pm)
+	}
+	r.closed = true
+}
+
+// remove must be called with r.mu held.
+func (r *Reassembler) remove(key reassemblyKey, pm *partialMessage) {
+	pm.timer.Stop()
+	delete(r.pending, key)
+	r.bytes -= pm.size
+}
+
+func (r *Reassembler) expire(key reassemblyKey, pm *partialMessage) {
+	r.mu.Lock()
+	if r.pending[key] != pm {
+		r.mu.Unlock()
+		return
+	}
+	r.remove(key, pm)
+	r.mu.Unlock()
+	if r.conf.OnTimeout != nil {
+		r.conf.OnTimeout(key.src, key.dst, key.ref, pm.received, len(pm.parts))
+	}
+}
+
+// DecodeText converts a short_message body in the given data_coding to
+// text. GSM7 bodies are expected unpacked and use the given shift tables.
+// Binary and unsupported data codings return an error.
+func DecodeText(dataCoding int, data []byte, locking, single NationalLanguage, mode GSM7Mode) (string, error) {
+	switch dc := byte(dataCoding); {
+	case dc == 0x00:
+		return DecodeGSM7Language(data, locking, single, mode)
+	case dc == 0x01:
+		return string(data), nil
+	case dc == byte(DataCodingLatin1):
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	}
+	switch ExtractUnicode(dataCoding) {
+	case DCT_UCS2:
+		return DecodeUCS2(data)
+	case DCT_Ascii_7_bit:
+		return DecodeGSM7Language(data, locking, single, mode)
+	case DCT_Binary:
+		return "", fmt.Errorf("data_coding 0x%02X is binary", dataCoding)
+	}
+	return "", fmt.Errorf("unsupported data_coding 0x%02X", dataCoding)
+}
@@ -0,0 +1,165 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/majiddarvishan/smpp/pdu"
+	"github.com/stretchr/testify/require"
+)
+
+// deliverParts turns submit_sm parts into the deliver_sm parts an SMSC
+// would hand over for them.
+func deliverParts(t *testing.T, b *SubmitSmBuilder, text string) []*pdu.DeliverSm {
+	parts, err := b.Build(&pdu.SubmitSm{SourceAddr: "111", DestinationAddr: "222"}, text)
+	require.NoError(t, err)
+	out := make([]*pdu.DeliverSm, len(parts))
+	for i, p := range parts {
+		out[i] = &pdu.DeliverSm{
+			SourceAddr:      p.SourceAddr,
+			DestinationAddr: p.DestinationAddr,
+			EsmClass:        p.EsmClass,
+			DataCoding:      p.DataCoding,
+			ShortMessage:    p.ShortMessage,
+			Options:         p.Options,
+		}
+	}
+	return out
+}
+
+func TestReassembler(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *SubmitSmBuilder
+		text string
+	}{
+		{"gsm7 udh8", &SubmitSmBuilder{Concatenation: ConcatUDH8}, strings.Repeat("Hello {world} ", 40)},
+		{"ucs2 udh16", &SubmitSmBuilder{Concatenation: ConcatUDH16}, strings.Repeat("سلام 😀 ", 40)},
+		{"latin1 sar", &SubmitSmBuilder{Concatenation: ConcatSAR, Latin1: true}, strings.Repeat("Ñandú ", 60)},
+		{"turkish udh8", &SubmitSmBuilder{Languages: []NationalLanguage{LanguageTurkish}}, strings.Repeat("Şimdi ığdır ", 40)},
+		{"payload", &SubmitSmBuilder{Concatenation: ConcatPayload}, strings.Repeat("payload ", 40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReassembler(ReassemblerConf{})
+			defer r.Close()
+			parts := deliverParts(t, tt.b, tt.text)
+
+			// Deliver in reverse order, with a duplicate of the last part.
+			var msg *Message
+			for i := len(parts) - 1; i >= 0; i-- {
+				require.Nil(t, msg)
+				var err error
+				msg, err = r.Add(parts[i])
+				require.NoError(t, err)
+				if i == len(parts)-1 && len(parts) > 1 {
+					dup, err := r.Add(parts[i])
+					require.NoError(t, err)
+					require.Nil(t, dup)
+				}
+			}
+			require.NotNil(t, msg)
+			require.Equal(t, tt.text, msg.Text)
+			require.Equal(t, len(parts), msg.Parts)
+			require.Equal(t, "111", msg.SourceAddr)
+			require.Equal(t, 0, r.Pending())
+		})
+	}
+}
+
+func TestReassemblerSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		langs []NationalLanguage
+		udhi  bool
+	}{
+		{"gsm7 single", "Hello {world}", nil, false},
+		{"gsm7", strings.Repeat("Hello {world} ", 40), nil, true},
+		{"national single", "Canción para él", []NationalLanguage{LanguageSpanish}, true},
+		{"national", strings.Repeat("Şimdi ığdır ", 40), []NationalLanguage{LanguageTurkish}, true},
+		{"ucs2", strings.Repeat("سلام 😀 ", 40), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReassembler(ReassemblerConf{})
+			defer r.Close()
+			parts, dcs, err := Split(tt.text, tt.langs...)
+			require.NoError(t, err)
+
+			var msg *Message
+			for _, part := range parts {
+				require.Nil(t, msg)
+				p := &pdu.DeliverSm{
+					SourceAddr:      "111",
+					DestinationAddr: "222",
+					DataCoding:      int(dcs),
+					ShortMessage:    string(part),
+				}
+				if tt.udhi {
+					p.EsmClass.Feature = pdu.UDHIEsmFeat
+				}
+				msg, err = r.Add(p)
+				require.NoError(t, err)
+			}
+			require.NotNil(t, msg)
+			require.Equal(t, tt.text, msg.Text)
+			require.Equal(t, len(parts), msg.Parts)
+		})
+	}
+}
+
+func TestReassemblerTimeout(t *testing.T) {
+	expired := make(chan [2]int, 1)
+	r := NewReassembler(ReassemblerConf{
+		Timeout: 20 * time.Millisecond,
+		OnTimeout: func(src, dst string, ref uint16, received, total int) {
+			expired <- [2]int{received, total}
+		},
+	})
+	defer r.Close()
+
+	parts := deliverParts(t, &SubmitSmBuilder{}, strings.Repeat("x", 400))
+	require.Len(t, parts, 3)
+	msg, err := r.Add(parts[0])
+	require.NoError(t, err)
+	require.Nil(t, msg)
+
+	select {
+	case got := <-expired:
+		require.Equal(t, [2]int{1, 3}, got)
+	case <-time.After(time.Second):
+		t.Fatal("OnTimeout not called")
+	}
+	require.Equal(t, 0, r.Pending())
+}
+
+func TestReassemblerLimits(t *testing.T) {
+	r := NewReassembler(ReassemblerConf{MaxPending: 1})
+	defer r.Close()
+
+	first := deliverParts(t, &SubmitSmBuilder{Concatenation: ConcatUDH16}, strings.Repeat("x", 400))
+	second := deliverParts(t, &SubmitSmBuilder{Concatenation: ConcatUDH16}, strings.Repeat("y", 400))
+	_, err := r.Add(first[0])
+	require.NoError(t, err)
+	_, err = r.Add(second[0])
+	require.Equal(t, ErrReassemblyFull, err)
+
+	r = NewReassembler(ReassemblerConf{MaxBytes: 200})
+	defer r.Close()
+	_, err = r.Add(first[0])
+	require.NoError(t, err)
+	_, err = r.Add(first[1])
+	require.Equal(t, ErrReassemblyFull, err)
+}
+
+func TestReassemblerInvalidPart(t *testing.T) {
+	r := NewReassembler(ReassemblerConf{})
+	defer r.Close()
+	_, err := r.Add(&pdu.DeliverSm{
+		EsmClass:     pdu.EsmClass{Feature: pdu.UDHIEsmFeat},
+		ShortMessage: string([]byte{0x05, 0x00, 0x03, 0x01, 0x02, 0x03, 'a'}),
+	})
+	require.Error(t, err)
+}
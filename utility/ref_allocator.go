@@ -0,0 +1,133 @@
+package utility
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// RefAllocator hands out concatenation reference numbers. Next returns a
+// reference for a new message to dst that fits in bits (8 or 16) bits.
+type RefAllocator interface {
+	Next(dst string, bits int) uint16
+}
+
+// RefAllocatorFunc adapts a function to RefAllocator, which is handy for
+// deterministic tests.
+type RefAllocatorFunc func(dst string, bits int) uint16
+
+// Next implements RefAllocator.
+func (f RefAllocatorFunc) Next(dst string, bits int) uint16 {
+	return f(dst, bits)
+}
+
+// DefaultRefAllocator is used when a Splitter or SubmitSmBuilder has no
+// RefAllocator. The package split functions do not know the destination
+// and allocate under the empty one.
+var DefaultRefAllocator RefAllocator = NewSequentialRefAllocator()
+
+// refIdle is how long a destination is remembered after its last message.
+const refIdle = time.Hour
+
+// NewSequentialRefAllocator returns an allocator that counts up per
+// destination from a random start, so a destination sees every reference
+// before one repeats.
+func NewSequentialRefAllocator() RefAllocator {
+	return &refAllocator{dests: make(map[refKey]*refState)}
+}
+
+// NewRandomRefAllocator returns an allocator that draws unpredictable
+// references per destination, avoiding the ones handed out most recently.
+func NewRandomRefAllocator() RefAllocator {
+	return &refAllocator{random: true, dests: make(map[refKey]*refState)}
+}
+
+type refAllocator struct {
+	random    bool
+	mu        sync.Mutex
+	dests     map[refKey]*refState
+	lastPrune time.Time
+}
+
+type refKey struct {
+	dst  string
+	bits int
+}
+
+type refState struct {
+	next   uint16
+	recent []uint16
+	used   time.Time
+}
+
+func (a *refAllocator) Next(dst string, bits int) uint16 {
+	if bits != 8 {
+		bits = 16
+	}
+	mask := uint16(1<<uint(bits) - 1)
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prune(now)
+	key := refKey{dst, bits}
+	st, ok := a.dests[key]
+	if !ok {
+		st = &refState{next: randomUint16() & mask}
+		a.dests[key] = st
+	}
+	st.used = now
+
+	if !a.random {
+		ref := st.next
+		st.next = (st.next + 1) & mask
+		return ref
+	}
+	// Keep the last quarter of the space, at most 64 references, out of
+	// reach of the next draws.
+	keep := int(mask/4) + 1
+	if keep > 64 {
+		keep = 64
+	}
+	ref := randomUint16() & mask
+	for containsRef(st.recent, ref) {
+		ref = randomUint16() & mask
+	}
+	st.recent = append(st.recent, ref)
+	if len(st.recent) > keep {
+		st.recent = st.recent[1:]
+	}
+	return ref
+}
+
+// prune forgets idle destinations; it must be called with a.mu held.
+func (a *refAllocator) prune(now time.Time) {
+	if now.Sub(a.lastPrune) < refIdle {
+		return
+	}
+	a.lastPrune = now
+	for key, st := range a.dests {
+		if now.Sub(st.used) > refIdle {
+			delete(a.dests, key)
+		}
+	}
+}
+
+func containsRef(refs []uint16, ref uint16) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// randomUint16 returns a cryptographically secure random number.
+func randomUint16() uint16 {
+	var b [2]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("utility: reading random bytes: " + err.Error())
+	}
+	return binary.BigEndian.Uint16(b[:])
+}
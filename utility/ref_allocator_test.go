@@ -0,0 +1,120 @@
+package utility
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/majiddarvishan/smpp/pdu"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequentialRefAllocator(t *testing.T) {
+	a := NewSequentialRefAllocator()
+	first := a.Next("111", 8)
+	require.True(t, first <= 0xFF)
+	for i := 1; i < 300; i++ {
+		require.Equal(t, (first+uint16(i))&0xFF, a.Next("111", 8))
+	}
+
+	// Destinations and reference sizes count independently.
+	other := a.Next("222", 8)
+	require.Equal(t, (other+1)&0xFF, a.Next("222", 8))
+	wide := a.Next("111", 16)
+	require.Equal(t, wide+1, a.Next("111", 16))
+}
+
+func TestRandomRefAllocator(t *testing.T) {
+	a := NewRandomRefAllocator()
+	var refs []uint16
+	for i := 0; i < 1000; i++ {
+		ref := a.Next("111", 8)
+		require.True(t, ref <= 0xFF)
+		start := len(refs) - 64
+		if start < 0 {
+			start = 0
+		}
+		require.NotContains(t, refs[start:], ref, "reference reused too early")
+		refs = append(refs, ref)
+	}
+}
+
+func TestRefAllocatorConcurrent(t *testing.T) {
+	a := NewSequentialRefAllocator()
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[uint16]bool)
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 32; j++ {
+				ref := a.Next("111", 8)
+				mu.Lock()
+				if seen[ref] {
+					t.Errorf("reference %d collided", ref)
+				}
+				seen[ref] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Len(t, seen, 256)
+}
+
+func TestBuildSubmitSm_RefAllocator(t *testing.T) {
+	var calls []string
+	b := &SubmitSmBuilder{
+		Concatenation: ConcatSAR,
+		Refs: RefAllocatorFunc(func(dst string, bits int) uint16 {
+			calls = append(calls, dst)
+			require.Equal(t, 16, bits)
+			return 0xBEEF
+		}),
+	}
+	parts, err := b.Build(&pdu.SubmitSm{DestinationAddr: "222"}, strings.Repeat("x", 200))
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	require.Equal(t, []string{"222"}, calls)
+	for _, p := range parts {
+		require.Equal(t, 0xBEEF, p.Options.SarMsgRefNum())
+	}
+
+	_, err = b.Build(&pdu.SubmitSm{DestinationAddr: "222"}, "short")
+	require.NoError(t, err)
+	require.Len(t, calls, 1, "single part allocated a reference")
+}
+
+func TestSplitter_RefAllocator(t *testing.T) {
+	var calls []string
+	sp := &Splitter{
+		Refs: RefAllocatorFunc(func(dst string, bits int) uint16 {
+			calls = append(calls, dst)
+			require.Equal(t, 8, bits)
+			return 0x42
+		}),
+	}
+	long := strings.Repeat("x", 200)
+	parts, _, err := sp.Split("222", long)
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	res, err := sp.SplitWithUDH("333", long)
+	require.NoError(t, err)
+	mpd, ok := res.UDHs[0].MultiPart()
+	require.True(t, ok)
+	require.Equal(t, uint16(0x42), mpd.Ref)
+	sp.SplitGSM7("444", long)
+	sp.SplitUCS2("555", long)
+	sp.SplitLatin1("666", long)
+	require.Equal(t, []string{"222", "333", "444", "555", "666"}, calls)
+	for _, p := range parts {
+		require.Equal(t, byte(0x42), p[3])
+	}
+
+	_, _, err = sp.Split("222", "short")
+	require.NoError(t, err)
+	require.Len(t, calls, 5, "single part allocated a reference")
+}
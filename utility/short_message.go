@@ -21,7 +21,9 @@ func UnpackShortMessage(dataCoding int, shortMessage string) (*UserDataHeader, s
 	}
 
 	udh := NewUserDataHeader()
-	udh.deserialize(shortMessage[1 : udhl+1])
+	if err := udh.deserialize(shortMessage[1 : udhl+1]); err != nil {
+		return nil, "", err
+	}
 	return udh, shortMessage[1+udhl:], nil
 }
 
@@ -1,54 +1,57 @@
 package utility
 
 import (
-	"encoding/binary"
 	"errors"
-	"math/rand"
-	"time"
-
-	"github.com/abadojack/whatlanggo"
+	"unicode/utf16"
 )
 
 // DataCoding represents SMPP data coding schemes.
 type DataCoding byte
 
 const (
-	DataCodingGSM7 DataCoding = 0x00 // GSM 7-bit encoding
-	DataCodingUCS2 DataCoding = 0x08 // UCS2 encoding (UCS-2 BE)
+	DataCodingGSM7   DataCoding = 0x00 // GSM 7-bit encoding
+	DataCodingLatin1 DataCoding = 0x03 // ISO-8859-1 encoding
+	DataCodingBinary DataCoding = 0x04 // 8-bit binary data
+	DataCodingUCS2   DataCoding = 0x08 // UCS2 encoding (UTF-16 BE)
+
+	// DataCodingBinaryClass1 is 8-bit binary data of message class 1,
+	// stored by the handset, as OTA configuration usually requires.
+	DataCodingBinaryClass1 DataCoding = 0xF5
 )
 
-// detectCoding returns the detected DataCoding (GSM7 or UCS2).
-func detectCoding(text string) DataCoding {
-	if IsEnglishOrEmoji(text) {
+// DetectCoding returns the data coding text needs: GSM7 when the default
+// alphabet and its extension table represent every character, Latin-1 when
+// latin1 is set and every character is in ISO-8859-1, and UCS2 otherwise.
+func DetectCoding(text string, latin1 bool) DataCoding {
+	if _, err := EncodeGSM7(text, GSM7Strict); err == nil {
 		return DataCodingGSM7
 	}
+	if latin1 && isLatin1(text) {
+		return DataCodingLatin1
+	}
 	return DataCodingUCS2
-
-	// for _, r := range text {
-	// 	if _, ok := gsm7Default[r]; ok {
-	// 		continue
-	// 	}
-	// 	if _, ok := gsm7Ext[r]; ok {
-	// 		continue
-	// 	}
-	// 	// any unsupported rune => UCS2
-	// 	return DataCodingUCS2
-	// }
-	// return DataCodingGSM7
 }
 
-// checks if the given text is in English
-func isEnglish(text string) bool {
-	if len(text) == 0 {
-		return true
+// encodeLatin1 converts text to ISO-8859-1, replacing the characters
+// outside it with '?'.
+func encodeLatin1(text string) []byte {
+	octets := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r > 0xFF {
+			r = '?'
+		}
+		octets = append(octets, byte(r))
 	}
-	info := whatlanggo.Detect(text)
+	return octets
+}
 
-	// Check if the detected language is English and if the detection is reliable
-	// The library provides a confidence score and a reliability flag.
-	// whatlanggo.Eng represents the English language code.
-	// info.IsReliable() gives an indication of the confidence.
-	return info.Lang == whatlanggo.Eng && info.IsReliable()
+func isLatin1(text string) bool {
+	for _, r := range text {
+		if r > 0xFF {
+			return false
+		}
+	}
+	return true
 }
 
 // chunkSeptets splits septets into size-limited slices, not ending on an ESC
@@ -72,8 +75,7 @@ func chunkSeptets(sep []byte, max int) [][]byte {
 
 // packSeptets packs 7-bit septets into octets
 func packSeptets(septets []byte) []byte {
-	bitLen := len(septets) * 7
-	octets := make([]byte, (bitLen+7)/8)
+	octets := make([]byte, (len(septets)*7+7)/8)
 	for i, s := range septets {
 		bitPos := uint(i * 7)
 		bytePos, offset := bitPos/8, bitPos%8
@@ -85,98 +87,140 @@ func packSeptets(septets []byte) []byte {
 	return octets
 }
 
-// randomByte returns a cryptographically secure random byte.
-func randomByte() byte {
-	source := rand.NewSource(time.Now().UnixNano()) // Create a new source
-	rng := rand.New(source)                         // Create a new random number generator
-	return byte(rng.Intn(256))
+// Splitter splits text like the package split functions, allocating the
+// concatenation references per destination. The zero value is ready to use.
+type Splitter struct {
+	// Languages are national language shift tables Split and SplitWithUDH
+	// try before giving up on GSM7.
+	Languages []NationalLanguage
+	// Latin1 lets Split and SplitWithUDH use data_coding 0x03 for text GSM7
+	// cannot represent.
+	Latin1 bool
+	// Graphemes keeps grapheme clusters within one part in UCS2.
+	Graphemes bool
+	// Refs allocates concatenation references. Defaults to
+	// DefaultRefAllocator.
+	Refs RefAllocator
+}
+
+// ref returns a new 8-bit concatenation reference for dst.
+func (sp *Splitter) ref(dst string) byte {
+	refs := sp.Refs
+	if refs == nil {
+		refs = DefaultRefAllocator
+	}
+	return byte(refs.Next(dst, 8))
 }
 
-// SplitUCS2 splits text into UCS-2 segments with 6-byte UDH, max 67 chars each.
+// SplitUCS2 splits text into UTF-16BE segments with 6-byte UDH, max 67
+// code units each. Characters outside the BMP are written as surrogate
+// pairs and never split across segments.
 func SplitUCS2(text string) [][]byte {
-	var parts [][]byte
+	return (&Splitter{}).SplitUCS2("", text)
+}
 
-	if len(text) <= 140 {
-		parts = append(parts, []byte(text))
-		return parts
-	}
+// SplitUCS2Graphemes is like SplitUCS2 but also avoids splitting grapheme
+// clusters, such as a letter and its combining marks or an emoji sequence,
+// across segments.
+func SplitUCS2Graphemes(text string) [][]byte {
+	return (&Splitter{Graphemes: true}).SplitUCS2("", text)
+}
 
+// SplitUCS2 is like the package SplitUCS2, with references allocated for
+// dst.
+func (sp *Splitter) SplitUCS2(dst, text string) [][]byte {
 	runes := []rune(text)
-	const maxChars = 67
-	total := (len(runes) + maxChars - 1) / maxChars
-	if total == 0 {
-		return nil
+	units := utf16.Encode(runes)
+	if len(units) <= 70 {
+		return [][]byte{encodeUTF16BE(units)}
+	}
+
+	var chunks [][]uint16
+	if sp.Graphemes {
+		chunks = chunkUTF16Graphemes(runes, 67)
+	} else {
+		chunks = chunkUTF16(units, 67)
 	}
 
-	// Generate a random reference number for the UDH
-	ref := randomByte()
+	ref := sp.ref(dst)
 
+	var parts [][]byte
+	for i, chunk := range chunks {
+		udh := []byte{0x05, 0x00, 0x03, ref, byte(len(chunks)), byte(i + 1)}
+		parts = append(parts, append(udh, encodeUTF16BE(chunk)...))
+	}
+	return parts
+}
+
+// SplitLatin1 splits text into ISO-8859-1 segments with 6-byte UDH, max
+// 134 characters each. Characters outside Latin-1 become '?'.
+func SplitLatin1(text string) [][]byte {
+	return (&Splitter{}).SplitLatin1("", text)
+}
+
+// SplitLatin1 is like the package SplitLatin1, with references allocated
+// for dst.
+func (sp *Splitter) SplitLatin1(dst, text string) [][]byte {
+	octets := encodeLatin1(text)
+	if len(octets) <= 140 {
+		return [][]byte{octets}
+	}
+
+	const maxChars = 134
+	total := (len(octets) + maxChars - 1) / maxChars
+	ref := sp.ref(dst)
+
+	var parts [][]byte
 	for i := 0; i < total; i++ {
 		start, end := i*maxChars, (i+1)*maxChars
-		if end > len(runes) {
-			end = len(runes)
+		if end > len(octets) {
+			end = len(octets)
 		}
 		udh := []byte{0x05, 0x00, 0x03, ref, byte(total), byte(i + 1)}
-		seg := runes[start:end]
-		ucs2 := make([]byte, len(seg)*2)
-		for j, r := range seg {
-			binary.BigEndian.PutUint16(ucs2[j*2:], uint16(r))
-		}
-		parts = append(parts, append(udh, ucs2...))
+		parts = append(parts, append(udh, octets[start:end]...))
 	}
 	return parts
 }
 
 // SplitGSM7 builds true GSM7 segments with UDH and full mapping
 func SplitGSM7(text string) [][]byte {
-	var parts [][]byte
+	return (&Splitter{}).SplitGSM7("", text)
+}
 
+// SplitGSM7 is like the package SplitGSM7, with references allocated for
+// dst.
+func (sp *Splitter) SplitGSM7(dst, text string) [][]byte {
 	// map runes to septets (including escapes)
-	var septets []byte
-	for _, r := range text {
-		if code, ok := gsm7Default[r]; ok {
-			septets = append(septets, code)
-		} else if ext, ok := gsm7Ext[r]; ok {
-			septets = append(septets, 0x1B, ext)
-		} else {
-			septets = append(septets, gsm7Default['?'])
-		}
-	}
+	septets, _ := EncodeGSM7(text, GSM7Lossy)
 
-	if len(septets) <= 160 {
-		parts = append(parts, septets)
-		return parts
-	}
-
-	// chunk septets into segments of max 153 septets, avoiding lone ESC
-	chunks := chunkSeptets(septets, 153)
-
-	// Generate a random reference number for the UDH
-	ref := randomByte()
+	// segments of max 153 septets, avoiding lone ESC
+	return sp.splitGSM7(dst, septets, gsm7Shift{})
+}
 
-	for i, chunk := range chunks {
-		udh := []byte{0x05, 0x00, 0x03, ref, byte(len(chunks)), byte(i + 1)}
-		packed := packSeptets(chunk)
-		parts = append(parts, append(udh, packed...))
-	}
-	return parts
+// Split encodes and splits text in GSM7 when the GSM default alphabet can
+// represent it, and in UCS2 otherwise. Text that would otherwise need UCS2
+// is kept in GSM7 when the shift tables of one of langs can represent it;
+// the parts then carry national language UDH elements. A Splitter with
+// Latin1 set can use Latin-1 instead of UCS2.
+func Split(text string, langs ...NationalLanguage) ([][]byte, DataCoding, error) {
+	return (&Splitter{Languages: langs}).Split("", text)
 }
 
-func Split(text string) ([][]byte, DataCoding, error) {
-	if len(text) == 0 {
-		return nil, 0x00, errors.New("empty message")
+// Split is like the package Split, trying sp.Languages, then Latin-1 if
+// sp.Latin1 is set, and allocating references for dst.
+func (sp *Splitter) Split(dst, text string) ([][]byte, DataCoding, error) {
+	res, err := sp.SplitWithUDH(dst, text)
+	if err != nil {
+		return nil, 0x00, err
 	}
-
-	coding := detectCoding(text)
-
-	switch coding {
-	case DataCodingGSM7:
-		return SplitGSM7(text), DataCodingGSM7, nil
-	case DataCodingUCS2:
-		return SplitUCS2(text), DataCodingUCS2, nil
-	default:
-		return nil, 0x00, errors.New("unknown data coding")
+	if len(res.UDHs) == 0 {
+		return res.Bodies, res.Coding, nil
+	}
+	parts := make([][]byte, len(res.Bodies))
+	for i, body := range res.Bodies {
+		parts[i] = append(res.UDHs[i].Pack(), body...)
 	}
+	return parts, res.Coding, nil
 }
 
 // UDH represents a 6-byte User Data Header for SMS concatenation.
@@ -217,79 +261,50 @@ type SplitResult struct {
 
 // SplitWithUDH splits the input text and returns a SplitResult struct
 // containing separate UDHs and body payloads for each segment.
-func SplitWithUDH(text string) (SplitResult, error) {
+// Like Split, it keeps text in GSM7 when the shift tables of one of langs
+// can represent it; the UDHs then announce the national language.
+func SplitWithUDH(text string, langs ...NationalLanguage) (SplitResult, error) {
+	return (&Splitter{Languages: langs}).SplitWithUDH("", text)
+}
+
+// SplitWithUDH is like the package SplitWithUDH, trying sp.Languages, then
+// Latin-1 if sp.Latin1 is set, and allocating references for dst.
+func (sp *Splitter) SplitWithUDH(dst, text string) (SplitResult, error) {
 	if len(text) == 0 {
 		return SplitResult{}, errors.New("empty message")
 	}
-	coding := detectCoding(text)
-	var result SplitResult
-	result.Coding = coding
-
-	switch coding {
-	case DataCodingGSM7:
-		// Build septets
-		//septets := make([]byte, 0, len(text)*2)
-		//for _, r := range text {
-		//	if code, ok := gsm7Default[r]; ok {
-		//		septets = append(septets, code)
-		//	} else if ext, ok := gsm7Ext[r]; ok {
-		//		septets = append(septets, 0x1B, ext)
-		//	} else {
-		//		septets = append(septets, gsm7Default['?'])
-		//	}
-		//}
-
-		if len(text) <= 160 {
-			result.Bodies = append(result.Bodies, []byte(text))
-			return result, nil
-		}
-
-		chunks := chunkSeptets([]byte(text), 153)
-
-		// Generate a random reference number for the UDH
-		ref := randomByte()
-		for i, chunk := range chunks {
-			mpd := MultiPartData{Ref: uint16(ref), Total: uint8(len(chunks)), Seq: uint8(i + 1)}
-			udh := NewUserDataHeader()
-			udh.SetMultiPartData(mpd)
-			result.UDHs = append(result.UDHs, *udh)
-			// s := UDH{UDHL: 0x05, IEI: 0x00, IEDL: 0x03, Ref: ref, Total: byte(len(chunks)), Seq: byte(i + 1)}
-			// result.UDHs = append(result.UDHs, s)
-
-			//result.Bodies = append(result.Bodies, packSeptets(chunk))
-			result.Bodies = append(result.Bodies, chunk)
-		}
-
-	case DataCodingUCS2:
-		if len(text) <= 140 {
-			result.Bodies = append(result.Bodies, []byte(text))
-			return result, nil
-		}
-
-		runes := []rune(text)
-		const maxChars = 67
-		total := (len(runes) + maxChars - 1) / maxChars
-		ref := randomByte()
-		for i := 0; i < total; i++ {
-			start := i * maxChars
-			end := start + maxChars
-			if end > len(runes) {
-				end = len(runes)
-			}
-			mpd := MultiPartData{Ref: uint16(ref), Total: uint8(total), Seq: uint8(i + 1)}
-			udh := NewUserDataHeader()
-			udh.SetMultiPartData(mpd)
-			result.UDHs = append(result.UDHs, *udh)
-
-			// s := UDH{UDHL: 0x05, IEI: 0x00, IEDL: 0x03, Ref: ref, Total: byte(total), Seq: byte(i + 1)}
-			// result.UDHs = append(result.UDHs, s)
-			seg := runes[start:end]
-			ucs2 := make([]byte, len(seg)*2)
-			for j, r := range seg {
-				binary.BigEndian.PutUint16(ucs2[j*2:], uint16(r))
-			}
-			result.Bodies = append(result.Bodies, ucs2)
+	b := sp.builder()
+	enc := b.encode(text)
+	chunks, _, err := b.split(enc, enc.shift.udhIEs())
+	if err != nil {
+		return SplitResult{}, err
+	}
+	result := SplitResult{Coding: enc.coding, Bodies: chunks}
+	if len(chunks) == 1 && enc.shift.ies() == 0 {
+		return result, nil
+	}
+	var ref byte
+	if len(chunks) > 1 {
+		ref = sp.ref(dst)
+	}
+	for i := range chunks {
+		udh := NewUserDataHeader()
+		if len(chunks) > 1 {
+			udh.SetMultiPartData(MultiPartData{Ref: uint16(ref), Total: uint8(len(chunks)), Seq: uint8(i + 1)})
 		}
+		udh.SetNationalLanguage(enc.shift.locking, enc.shift.single)
+		result.UDHs = append(result.UDHs, *udh)
 	}
 	return result, nil
 }
+
+// builder returns a SubmitSmBuilder encoding and chunking text like sp, so
+// Split and Build agree on parts.
+func (sp *Splitter) builder() *SubmitSmBuilder {
+	return &SubmitSmBuilder{
+		Languages: sp.Languages,
+		Latin1:    sp.Latin1,
+		Graphemes: sp.Graphemes,
+		Refs:      sp.Refs,
+	}
+}
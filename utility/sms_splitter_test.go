@@ -9,9 +9,18 @@ import (
 )
 
 func TestDetectCoding(t *testing.T) {
-	require.Equal(t, DataCodingGSM7, detectCoding("Hello World!"))
-	require.Equal(t, DataCodingUCS2, detectCoding("سلام دنیا"))
-	require.Equal(t, DataCodingUCS2, detectCoding("Hello 👋"))
+	require.Equal(t, DataCodingGSM7, DetectCoding("Hello World!", false))
+	require.Equal(t, DataCodingUCS2, DetectCoding("سلام دنیا", false))
+	require.Equal(t, DataCodingUCS2, DetectCoding("Hello 👋", false))
+	require.Equal(t, DataCodingUCS2, DetectCoding("Ñandú ô", false))
+	require.Equal(t, DataCodingLatin1, DetectCoding("Ñandú ô", true))
+	require.Equal(t, DataCodingUCS2, DetectCoding("Ñandú 👋", true))
+}
+
+func TestIsEnglishOrEmoji(t *testing.T) {
+	require.True(t, IsEnglishOrEmoji("Hello World!\n"))
+	require.True(t, IsEnglishOrEmoji("Hello 👋"))
+	require.False(t, IsEnglishOrEmoji("سلام دنیا"))
 }
 
 func TestSplit_Empty(t *testing.T) {
@@ -100,3 +109,24 @@ func TestSplitWithUDH_UCS2(t *testing.T) {
 		require.NotEmpty(t, res.Bodies[i], "Body payload should not be empty")
 	}
 }
+
+func TestSplit_Latin1(t *testing.T) {
+	sp := &Splitter{Latin1: true}
+	parts, dcs, err := sp.Split("", "Ñandú ô")
+	require.NoError(t, err)
+	require.Equal(t, DataCodingLatin1, dcs)
+	require.Equal(t, [][]byte{{0xD1, 'a', 'n', 'd', 0xFA, ' ', 0xF4}}, parts)
+
+	_, dcs, err = Split("Ñandú ô")
+	require.NoError(t, err)
+	require.Equal(t, DataCodingUCS2, dcs, "Latin-1 is opt-in")
+
+	text := strings.Repeat("Ñandú ô ", 30)
+	res, err := sp.SplitWithUDH("", text)
+	require.NoError(t, err)
+	require.Equal(t, DataCodingLatin1, res.Coding)
+	require.Len(t, res.UDHs, 2)
+	require.Len(t, res.Bodies, 2)
+	require.Len(t, res.Bodies[0], 134)
+	require.Equal(t, encodeLatin1(text), append(res.Bodies[0], res.Bodies[1]...))
+}
@@ -0,0 +1,131 @@
+package utility
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode"
+	"unicode/utf16"
+)
+
+// EncodeUCS2 converts text to UTF-16BE, writing characters outside the BMP
+// as surrogate pairs.
+func EncodeUCS2(text string) []byte {
+	return encodeUTF16BE(utf16.Encode([]rune(text)))
+}
+
+// DecodeUCS2 converts UTF-16BE data, such as a short_message sent with
+// data_coding 0x08, back to text. Unpaired surrogates become U+FFFD.
+func DecodeUCS2(data []byte) (string, error) {
+	if len(data)%2 != 0 {
+		return "", fmt.Errorf("ucs2: odd data length %d", len(data))
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// encodeUTF16BE writes UTF-16 code units big-endian.
+func encodeUTF16BE(units []uint16) []byte {
+	out := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.BigEndian.PutUint16(out[i*2:], u)
+	}
+	return out
+}
+
+// chunkUTF16 splits code units into size-limited slices, not ending on a
+// high surrogate so that surrogate pairs stay together.
+func chunkUTF16(units []uint16, max int) [][]uint16 {
+	var out [][]uint16
+	i := 0
+	for i < len(units) {
+		limit := max
+		if rest := len(units) - i; rest < max {
+			limit = rest
+		}
+		if limit > 1 && utf16.IsSurrogate(rune(units[i+limit-1])) && units[i+limit-1] < 0xDC00 {
+			limit--
+		}
+		out = append(out, units[i:i+limit])
+		i += limit
+	}
+	return out
+}
+
+// chunkUTF16Graphemes is like chunkUTF16 but also keeps grapheme clusters
+// together. A cluster longer than max is split between surrogate pairs.
+func chunkUTF16Graphemes(runes []rune, max int) [][]uint16 {
+	units := utf16.Encode(runes)
+	breaks := graphemeBreaks(runes)
+	// starts[j] is true when a cluster may start at code unit j.
+	starts := make([]bool, len(units)+1)
+	j := 0
+	for i, r := range runes {
+		starts[j] = breaks[i]
+		j += len(utf16.Encode([]rune{r}))
+	}
+	starts[len(units)] = true
+
+	var out [][]uint16
+	i := 0
+	for i < len(units) {
+		limit := max
+		if rest := len(units) - i; rest < max {
+			limit = rest
+		}
+		end := i + limit
+		for end > i && !starts[end] {
+			end--
+		}
+		if end == i {
+			out = append(out, chunkUTF16(units[i:i+limit], max)[0])
+			i += len(out[len(out)-1])
+			continue
+		}
+		out = append(out, units[i:end])
+		i = end
+	}
+	return out
+}
+
+// graphemeBreaks reports for each rune whether a grapheme cluster may start
+// at it. It approximates UAX #29: combining marks, joiners, variation
+// selectors, emoji modifiers and tags extend the previous cluster, emoji
+// ZWJ sequences and regional indicator pairs stay together, as does CR LF.
+func graphemeBreaks(runes []rune) []bool {
+	breaks := make([]bool, len(runes))
+	ri := 0
+	for i, r := range runes {
+		isRI := r >= 0x1F1E6 && r <= 0x1F1FF
+		switch {
+		case i == 0:
+			breaks[i] = true
+		case runes[i-1] == '\r' && r == '\n':
+		case isGraphemeExtend(r):
+		case runes[i-1] == 0x200D && isEmojiRune(r):
+		case isRI && ri%2 == 1:
+		default:
+			breaks[i] = true
+		}
+		if isRI {
+			ri++
+		} else {
+			ri = 0
+		}
+	}
+	return breaks
+}
+
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case r == 0x200D, r == 0x20E3:
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F, r >= 0xE0100 && r <= 0xE01EF:
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF, r >= 0xE0020 && r <= 0xE007F:
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc)
+}
@@ -0,0 +1,100 @@
+package utility
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectCodingRepresentability(t *testing.T) {
+	require.Equal(t, DataCodingGSM7, DetectCoding("Café {Ñ}", false))
+	require.Equal(t, DataCodingUCS2, DetectCoding("😀", true))
+	require.Equal(t, DataCodingUCS2, DetectCoding("Ñandú", false))
+	require.Equal(t, DataCodingLatin1, DetectCoding("Ñandú", true))
+}
+
+func TestSplitUCS2_Surrogates(t *testing.T) {
+	parts := SplitUCS2("a😀")
+	require.Equal(t, [][]byte{{0x00, 'a', 0xD8, 0x3D, 0xDE, 0x00}}, parts)
+
+	text := strings.Repeat("a", 66) + strings.Repeat("😀", 10)
+	parts = SplitUCS2(text)
+	require.Len(t, parts, 2)
+	require.Len(t, parts[0], 6+66*2, "pair must not straddle the boundary")
+
+	var units []uint16
+	for _, p := range parts {
+		require.True(t, len(p) <= 140)
+		body := p[6:]
+		for i := 0; i < len(body); i += 2 {
+			units = append(units, uint16(body[i])<<8|uint16(body[i+1]))
+		}
+	}
+	require.Equal(t, text, string(utf16.Decode(units)))
+}
+
+func TestSplitLatin1(t *testing.T) {
+	require.Equal(t, [][]byte{{0xD1, 'a', 'n', 'd', 0xFA}}, SplitLatin1("Ñandú"))
+
+	parts := SplitLatin1(strings.Repeat("é", 200))
+	require.Len(t, parts, 2)
+	require.Len(t, parts[0], 140)
+	require.Equal(t, byte(0xE9), parts[1][6])
+}
+
+func TestSplit_GSM7Accents(t *testing.T) {
+	parts, dcs, err := Split("Café")
+	require.NoError(t, err)
+	require.Equal(t, DataCodingGSM7, dcs)
+	require.Equal(t, [][]byte{{'C', 'a', 'f', 0x05}}, parts)
+}
+
+func TestDecodeUCS2(t *testing.T) {
+	for _, text := range []string{"", "سلام", "a😀b", "👨‍👩‍👧"} {
+		decoded, err := DecodeUCS2(EncodeUCS2(text))
+		require.NoError(t, err)
+		require.Equal(t, text, decoded)
+	}
+
+	decoded, err := DecodeUCS2([]byte{0xD8, 0x3D, 0x00, 'a'})
+	require.NoError(t, err)
+	require.Equal(t, "�a", decoded)
+
+	_, err = DecodeUCS2([]byte{0x00, 'a', 0x00})
+	require.Error(t, err)
+}
+
+func TestSplitUCS2Graphemes(t *testing.T) {
+	decode := func(parts [][]byte) []string {
+		var out []string
+		for _, p := range parts {
+			require.True(t, len(p) <= 140)
+			s, err := DecodeUCS2(p[6:])
+			require.NoError(t, err)
+			out = append(out, s)
+		}
+		return out
+	}
+
+	text := strings.Repeat("a", 66) + "é" + strings.Repeat("b", 10)
+	require.Equal(t, []string{strings.Repeat("a", 66) + "e", "́" + strings.Repeat("b", 10)},
+		decode(SplitUCS2(text)))
+	require.Equal(t, []string{strings.Repeat("a", 66), "é" + strings.Repeat("b", 10)},
+		decode(SplitUCS2Graphemes(text)))
+
+	family := "👨‍👩‍👧"
+	text = strings.Repeat("a", 60) + family + strings.Repeat("b", 10)
+	parts := decode(SplitUCS2Graphemes(text))
+	require.Equal(t, []string{strings.Repeat("a", 60), family + strings.Repeat("b", 10)}, parts)
+
+	flags := strings.Repeat("🇮🇷", 40)
+	for _, p := range decode(SplitUCS2Graphemes(flags)) {
+		require.Equal(t, 0, len([]rune(p))%2, "regional indicator pair split")
+	}
+
+	// A cluster longer than a segment still splits, between surrogate pairs.
+	long := "a" + strings.Repeat("́", 100)
+	require.Equal(t, long, strings.Join(decode(SplitUCS2Graphemes(long)), ""))
+}
@@ -5,6 +5,9 @@ import "unicode"
 // IsEnglishOrEmoji returns true if s contains only ASCII English text
 // (letters, digits, common punctuation/whitespace) and/or emojis.
 // It rejects letters from other scripts (e.g., Arabic, Cyrillic, CJK).
+//
+// Deprecated: it doesn't tell which data coding s needs, use DetectCoding
+// or let Splitter pick the coding instead.
 func IsEnglishOrEmoji(s string) bool {
 	for _, r := range s {
 		if isASCIIEnglishAllowed(r) || isEmojiRune(r) {
@@ -4,111 +4,359 @@ import (
 	"fmt"
 )
 
+// Information element identifiers, see 3GPP 23.040 section 9.2.3.24.
 const (
-	concatenated_sm_8bit_ref  uint8 = 0x00
-	concatenated_sm_16bit_ref uint8 = 0x08
+	IEIConcatenated8Bit     uint8 = 0x00
+	IEISpecialSMS           uint8 = 0x01
+	IEIPort8Bit             uint8 = 0x04
+	IEIPort16Bit            uint8 = 0x05
+	IEIConcatenated16Bit    uint8 = 0x08
+	IEITextFormatting       uint8 = 0x0A
+	IEIPredefinedSound      uint8 = 0x0B
+	IEIUserDefinedSound     uint8 = 0x0C
+	IEIPredefinedAnimation  uint8 = 0x0D
+	IEILargeAnimation       uint8 = 0x0E
+	IEISmallAnimation       uint8 = 0x0F
+	IEILargePicture         uint8 = 0x10
+	IEISmallPicture         uint8 = 0x11
+	IEIVariablePicture      uint8 = 0x12
+	IEINationalSingleShift  uint8 = 0x24
+	IEINationalLockingShift uint8 = 0x25
 )
 
+// ieLength holds the data length of fixed size information elements.
+var ieLength = map[uint8]int{
+	IEIConcatenated8Bit:     3,
+	IEISpecialSMS:           2,
+	IEIPort8Bit:             2,
+	IEIPort16Bit:            4,
+	IEIConcatenated16Bit:    4,
+	IEINationalSingleShift:  1,
+	IEINationalLockingShift: 1,
+}
+
+// ieSingle lists information elements that may appear at most once.
+// Concatenation and port addressing also exclude their other size.
+var ieSingle = map[uint8]uint8{
+	IEIConcatenated8Bit:     IEIConcatenated16Bit,
+	IEIConcatenated16Bit:    IEIConcatenated8Bit,
+	IEIPort8Bit:             IEIPort16Bit,
+	IEIPort16Bit:            IEIPort8Bit,
+	IEINationalSingleShift:  IEINationalSingleShift,
+	IEINationalLockingShift: IEINationalLockingShift,
+}
+
+// InformationElement is one element of a User Data Header.
+type InformationElement struct {
+	ID   uint8
+	Data []byte
+}
+
 type MultiPartData struct {
 	Ref   uint16 // Concatenation reference number
 	Total uint8  // Total number of segments
 	Seq   uint8  // Sequence number of this segment
 }
 
+// SpecialSMSIndication signals waiting voice, fax, email or other messages.
+type SpecialSMSIndication struct {
+	Store bool  // store the message after updating the indication
+	Type  uint8 // message indication type and extension, bits 0-6
+	Count uint8 // number of waiting messages
+}
+
+// TextFormatting is an EMS text formatting element.
+type TextFormatting struct {
+	Start  uint8
+	Length uint8
+	Mode   uint8  // alignment, font size and style bits
+	Color  *uint8 // optional foreground and background color
+}
+
+// EMSObject is an EMS sound, animation or picture placed at a position
+// in the text.
+type EMSObject struct {
+	ID       uint8
+	Position uint8
+	Data     []byte
+}
+
+// UserDataHeader is an ordered list of information elements.
 type UserDataHeader struct {
-	informationElements map[uint8]string
+	elements []InformationElement
 }
 
 func NewUserDataHeader() *UserDataHeader {
-	return &UserDataHeader{
-		informationElements: make(map[uint8]string),
-	}
+	return &UserDataHeader{}
 }
 
-func (udh *UserDataHeader) deserialize(buf string) error {
-	var headerLen uint8 = 2
+// ParseUserDataHeader splits user data, such as a short_message sent with
+// UDHI set, into its header and the remaining body. It fails on truncated
+// elements, wrong lengths of fixed size elements, repeated elements that
+// may appear once and invalid concatenation values.
+func ParseUserDataHeader(ud []byte) (*UserDataHeader, []byte, error) {
+	if len(ud) == 0 {
+		return nil, nil, fmt.Errorf("user_data_header: empty user data")
+	}
+	udhl := int(ud[0])
+	if udhl >= len(ud) {
+		return nil, nil, fmt.Errorf("user_data_header: UDHL %d exceeds user data length %d", udhl, len(ud)-1)
+	}
+	udh := NewUserDataHeader()
+	if err := udh.parse(ud[1 : 1+udhl]); err != nil {
+		return nil, nil, err
+	}
+	return udh, ud[1+udhl:], nil
+}
 
-	for {
-		if len(buf) < int(headerLen) {
-			break
+func (udh *UserDataHeader) parse(buf []byte) error {
+	udh.elements = nil
+	for off := 0; off < len(buf); {
+		if len(buf)-off < 2 {
+			return fmt.Errorf("user_data_header: truncated element at offset %d", off)
 		}
+		iei, iedl := buf[off], int(buf[off+1])
+		if off+2+iedl > len(buf) {
+			return fmt.Errorf("user_data_header: element 0x%02X length %d exceeds header", iei, iedl)
+		}
+		if l, ok := ieLength[iei]; ok && l != iedl {
+			return fmt.Errorf("user_data_header: element 0x%02X has length %d, want %d", iei, iedl, l)
+		}
+		if other, ok := ieSingle[iei]; ok {
+			if _, dup := udh.Get(iei); dup {
+				return fmt.Errorf("user_data_header: element 0x%02X repeated", iei)
+			}
+			if _, dup := udh.Get(other); dup {
+				return fmt.Errorf("user_data_header: elements 0x%02X and 0x%02X both present", iei, other)
+			}
+		}
+		data := append([]byte(nil), buf[off+2:off+2+iedl]...)
+		udh.elements = append(udh.elements, InformationElement{ID: iei, Data: data})
+		off += 2 + iedl
+	}
+	if mpd, ok := udh.MultiPart(); ok && (mpd.Total == 0 || mpd.Seq == 0 || mpd.Seq > mpd.Total) {
+		return fmt.Errorf("user_data_header: invalid concatenation part %d of %d", mpd.Seq, mpd.Total)
+	}
+	return nil
+}
 
-		iei := uint8(buf[0])
-		iedl := uint8(buf[1])
+func (udh *UserDataHeader) deserialize(buf string) error {
+	return udh.parse([]byte(buf))
+}
 
-		if int(iedl) > len(buf)-int(headerLen) {
-			return fmt.Errorf("user_data_header IEDL is bigger than available buf")
-		}
+func (udh *UserDataHeader) serialize() string {
+	var buf []byte
+	for _, ie := range udh.elements {
+		buf = append(buf, ie.ID, uint8(len(ie.Data)))
+		buf = append(buf, ie.Data...)
+	}
+	return string(buf)
+}
 
-		ied := buf[headerLen : headerLen+iedl]
+// Pack returns the header, UDHL included, or nil if it has no elements.
+func (udh *UserDataHeader) Pack() []byte {
+	ies := udh.serialize()
+	if len(ies) == 0 {
+		return nil
+	}
+	return append([]byte{uint8(len(ies))}, ies...)
+}
 
-		if (iei == concatenated_sm_8bit_ref && len(ied) != 3) || (iei == concatenated_sm_16bit_ref && len(ied) != 4) {
-			return fmt.Errorf("MultiPartData length in UDH is invalid")
+// Elements returns the information elements in order.
+func (udh *UserDataHeader) Elements() []InformationElement {
+	return udh.elements
+}
+
+// Get returns the data of the first element with the given identifier.
+func (udh *UserDataHeader) Get(id uint8) ([]byte, bool) {
+	for _, ie := range udh.elements {
+		if ie.ID == id {
+			return ie.Data, true
 		}
+	}
+	return nil, false
+}
 
-		udh.informationElements[iei] = ied
+// Add appends an element, keeping any with the same identifier.
+func (udh *UserDataHeader) Add(id uint8, data []byte) {
+	udh.elements = append(udh.elements, InformationElement{ID: id, Data: data})
+}
 
-		if len(buf) <= int(headerLen+iedl) {
-			break
+// Set replaces the elements with the given identifier by a single one,
+// placed where the first of them was.
+func (udh *UserDataHeader) Set(id uint8, data []byte) {
+	for i, ie := range udh.elements {
+		if ie.ID == id {
+			udh.elements[i].Data = data
+			udh.elements = append(udh.elements[:i+1], removeIE(udh.elements[i+1:], id)...)
+			return
 		}
-		buf = string(buf[headerLen+iedl])
 	}
-
-	return nil
+	udh.Add(id, data)
 }
 
-func (udh *UserDataHeader) serialize() string {
-	var buf []byte
+// Remove drops all elements with the given identifier.
+func (udh *UserDataHeader) Remove(id uint8) {
+	udh.elements = removeIE(udh.elements, id)
+}
 
-	for tag, val := range udh.informationElements {
-		buf = append(buf, uint8(tag))
-		buf = append(buf, uint8(len(val)))
-		buf = append(buf, []byte(val)...)
+func removeIE(ies []InformationElement, id uint8) []InformationElement {
+	out := ies[:0]
+	for _, ie := range ies {
+		if ie.ID != id {
+			out = append(out, ie)
+		}
 	}
-	return string(buf)
+	return out
 }
 
+// SetMultiPartData sets the concatenation element, 16-bit if the
+// reference does not fit in 8 bits.
 func (udh *UserDataHeader) SetMultiPartData(mpd MultiPartData) {
+	udh.Remove(IEIConcatenated8Bit)
+	udh.Remove(IEIConcatenated16Bit)
 	if mpd.Ref > 0xFF {
-		var val []byte
-		val = append(val, uint8((mpd.Ref>>8)&0xFF))
-		val = append(val, uint8((mpd.Ref>>0)&0xFF))
-		val = append(val, mpd.Total)
-		val = append(val, mpd.Seq)
-
-		udh.informationElements[concatenated_sm_16bit_ref] = string(val)
+		udh.Add(IEIConcatenated16Bit, []byte{uint8(mpd.Ref >> 8), uint8(mpd.Ref), mpd.Total, mpd.Seq})
 	} else {
-		var val []byte
-		val = append(val, uint8(mpd.Ref))
-		val = append(val, mpd.Total)
-		val = append(val, mpd.Seq)
+		udh.Add(IEIConcatenated8Bit, []byte{uint8(mpd.Ref), mpd.Total, mpd.Seq})
+	}
+}
 
-		udh.informationElements[concatenated_sm_8bit_ref] = string(val)
+// MultiPart returns the concatenation element, if any.
+func (udh *UserDataHeader) MultiPart() (MultiPartData, bool) {
+	if val, ok := udh.Get(IEIConcatenated8Bit); ok && len(val) == 3 {
+		return MultiPartData{Ref: uint16(val[0]), Total: val[1], Seq: val[2]}, true
+	}
+	if val, ok := udh.Get(IEIConcatenated16Bit); ok && len(val) == 4 {
+		return MultiPartData{Ref: uint16(val[0])<<8 | uint16(val[1]), Total: val[2], Seq: val[3]}, true
 	}
+	return MultiPartData{}, false
 }
 
+// GetMultiPartData returns the concatenation element, or part 1 of 1 if
+// there is none.
 func (udh *UserDataHeader) GetMultiPartData() MultiPartData {
-	val, ok := udh.informationElements[concatenated_sm_8bit_ref]
-	if ok {
-		return MultiPartData{
-			Ref:   uint16(val[0]),
-			Total: val[1],
-			Seq:   val[2],
+	if mpd, ok := udh.MultiPart(); ok {
+		return mpd
+	}
+	return MultiPartData{
+		Ref:   0,
+		Total: 1,
+		Seq:   1,
+	}
+}
+
+// SetNationalLanguage announces the locking and single shift tables the
+// body is encoded with. LanguageDefault tables are not announced.
+func (udh *UserDataHeader) SetNationalLanguage(locking, single NationalLanguage) {
+	udh.Remove(IEINationalLockingShift)
+	udh.Remove(IEINationalSingleShift)
+	if single != LanguageDefault {
+		udh.Add(IEINationalSingleShift, []byte{byte(single)})
+	}
+	if locking != LanguageDefault {
+		udh.Add(IEINationalLockingShift, []byte{byte(locking)})
+	}
+}
+
+// GetNationalLanguage returns the announced locking and single shift tables.
+func (udh *UserDataHeader) GetNationalLanguage() (locking, single NationalLanguage) {
+	if val, ok := udh.Get(IEINationalLockingShift); ok && len(val) == 1 {
+		locking = NationalLanguage(val[0])
+	}
+	if val, ok := udh.Get(IEINationalSingleShift); ok && len(val) == 1 {
+		single = NationalLanguage(val[0])
+	}
+	return locking, single
+}
+
+// SetPorts8 sets 8-bit application port addressing.
+func (udh *UserDataHeader) SetPorts8(dst, src uint8) {
+	udh.Remove(IEIPort16Bit)
+	udh.Set(IEIPort8Bit, []byte{dst, src})
+}
+
+// SetPorts16 sets 16-bit application port addressing.
+func (udh *UserDataHeader) SetPorts16(dst, src uint16) {
+	udh.Remove(IEIPort8Bit)
+	udh.Set(IEIPort16Bit, []byte{uint8(dst >> 8), uint8(dst), uint8(src >> 8), uint8(src)})
+}
+
+// Ports returns the destination and source application ports, 8- or 16-bit.
+func (udh *UserDataHeader) Ports() (dst, src uint16, ok bool) {
+	if val, ok := udh.Get(IEIPort16Bit); ok && len(val) == 4 {
+		return uint16(val[0])<<8 | uint16(val[1]), uint16(val[2])<<8 | uint16(val[3]), true
+	}
+	if val, ok := udh.Get(IEIPort8Bit); ok && len(val) == 2 {
+		return uint16(val[0]), uint16(val[1]), true
+	}
+	return 0, 0, false
+}
+
+// AddSpecialSMSIndication appends a special SMS message indication. A
+// header may carry one per indication type.
+func (udh *UserDataHeader) AddSpecialSMSIndication(ind SpecialSMSIndication) {
+	b := ind.Type & 0x7F
+	if ind.Store {
+		b |= 0x80
+	}
+	udh.Add(IEISpecialSMS, []byte{b, ind.Count})
+}
+
+// SpecialSMSIndications returns the special SMS message indications.
+func (udh *UserDataHeader) SpecialSMSIndications() []SpecialSMSIndication {
+	var out []SpecialSMSIndication
+	for _, ie := range udh.elements {
+		if ie.ID == IEISpecialSMS && len(ie.Data) == 2 {
+			out = append(out, SpecialSMSIndication{
+				Store: ie.Data[0]&0x80 != 0,
+				Type:  ie.Data[0] & 0x7F,
+				Count: ie.Data[1],
+			})
 		}
 	}
+	return out
+}
+
+// AddTextFormatting appends an EMS text formatting element.
+func (udh *UserDataHeader) AddTextFormatting(tf TextFormatting) {
+	data := []byte{tf.Start, tf.Length, tf.Mode}
+	if tf.Color != nil {
+		data = append(data, *tf.Color)
+	}
+	udh.Add(IEITextFormatting, data)
+}
 
-	val, ok = udh.informationElements[concatenated_sm_16bit_ref]
-	if ok {
-		return MultiPartData{
-			Ref:   uint16(val[0]<<8 | val[1]),
-			Total: val[2],
-			Seq:   val[3],
+// TextFormattings returns the EMS text formatting elements.
+func (udh *UserDataHeader) TextFormattings() []TextFormatting {
+	var out []TextFormatting
+	for _, ie := range udh.elements {
+		if ie.ID != IEITextFormatting || len(ie.Data) < 3 {
+			continue
+		}
+		tf := TextFormatting{Start: ie.Data[0], Length: ie.Data[1], Mode: ie.Data[2]}
+		if len(ie.Data) > 3 {
+			c := ie.Data[3]
+			tf.Color = &c
 		}
+		out = append(out, tf)
 	}
+	return out
+}
 
-	return MultiPartData{
-		Ref:   0,
-		Total: 1,
-		Seq:   1,
+// AddEMSObject appends an EMS sound, animation or picture element.
+func (udh *UserDataHeader) AddEMSObject(obj EMSObject) {
+	udh.Add(obj.ID, append([]byte{obj.Position}, obj.Data...))
+}
+
+// EMSObjects returns the EMS sound, animation and picture elements.
+func (udh *UserDataHeader) EMSObjects() []EMSObject {
+	var out []EMSObject
+	for _, ie := range udh.elements {
+		if ie.ID < IEIPredefinedSound || ie.ID > IEIVariablePicture || len(ie.Data) == 0 {
+			continue
+		}
+		out = append(out, EMSObject{ID: ie.ID, Position: ie.Data[0], Data: ie.Data[1:]})
 	}
+	return out
 }
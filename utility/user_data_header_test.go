@@ -0,0 +1,98 @@
+package utility
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserDataHeader(t *testing.T) {
+	ud := []byte{
+		0x11,
+		0x01, 0x02, 0x81, 0x03, // special SMS: store, fax, 3 waiting
+		0x08, 0x04, 0x12, 0x34, 0x03, 0x02, // concatenation, 16-bit ref
+		0x01, 0x02, 0x00, 0x01, // special SMS: voice, 1 waiting
+		0x25, 0x01, 0x01, // Turkish locking shift
+		'h', 'i',
+	}
+	udh, body, err := ParseUserDataHeader(ud)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hi"), body)
+
+	var ids []uint8
+	for _, ie := range udh.Elements() {
+		ids = append(ids, ie.ID)
+	}
+	require.Equal(t, []uint8{0x01, 0x08, 0x01, 0x25}, ids)
+
+	mpd, ok := udh.MultiPart()
+	require.True(t, ok)
+	require.Equal(t, MultiPartData{Ref: 0x1234, Total: 3, Seq: 2}, mpd)
+	require.Equal(t, []SpecialSMSIndication{
+		{Store: true, Type: 0x01, Count: 3},
+		{Store: false, Type: 0x00, Count: 1},
+	}, udh.SpecialSMSIndications())
+	locking, single := udh.GetNationalLanguage()
+	require.Equal(t, LanguageTurkish, locking)
+	require.Equal(t, LanguageDefault, single)
+
+	require.Equal(t, ud[:len(ud)-2], udh.Pack())
+}
+
+func TestParseUserDataHeader_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		ud   []byte
+	}{
+		{"empty", nil},
+		{"udhl too long", []byte{0x05, 0x00, 0x03}},
+		{"truncated element", []byte{0x03, 0x00, 0x03, 0x01}},
+		{"dangling identifier", []byte{0x01, 0x00}},
+		{"bad concat length", []byte{0x04, 0x00, 0x02, 0x01, 0x01}},
+		{"bad port length", []byte{0x04, 0x05, 0x02, 0x01, 0x01}},
+		{"repeated concat", []byte{0x0A, 0x00, 0x03, 0x01, 0x02, 0x01, 0x00, 0x03, 0x01, 0x02, 0x02}},
+		{"8 and 16-bit ports", []byte{0x0A, 0x04, 0x02, 0x01, 0x01, 0x05, 0x04, 0x00, 0x01, 0x00, 0x01}},
+		{"sequence zero", []byte{0x05, 0x00, 0x03, 0x01, 0x02, 0x00}},
+		{"sequence past total", []byte{0x05, 0x00, 0x03, 0x01, 0x02, 0x03}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseUserDataHeader(tt.ud)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestUserDataHeaderHelpers(t *testing.T) {
+	udh := NewUserDataHeader()
+	require.Nil(t, udh.Pack())
+
+	udh.SetPorts8(1, 2)
+	udh.SetPorts16(0x0B84, 0x23F0)
+	dst, src, ok := udh.Ports()
+	require.True(t, ok)
+	require.Equal(t, uint16(0x0B84), dst)
+	require.Equal(t, uint16(0x23F0), src)
+
+	udh.SetMultiPartData(MultiPartData{Ref: 7, Total: 2, Seq: 1})
+	udh.SetMultiPartData(MultiPartData{Ref: 0x0107, Total: 2, Seq: 2})
+	color := uint8(0x20)
+	udh.AddTextFormatting(TextFormatting{Start: 0, Length: 5, Mode: 0x10, Color: &color})
+	udh.AddEMSObject(EMSObject{ID: IEIPredefinedSound, Position: 3, Data: []byte{0x01}})
+	udh.SetNationalLanguage(LanguageDefault, LanguageSpanish)
+
+	parsed, body, err := ParseUserDataHeader(append(udh.Pack(), 'x'))
+	require.NoError(t, err)
+	require.Equal(t, []byte("x"), body)
+	require.Equal(t, udh.Elements(), parsed.Elements())
+	require.Equal(t, MultiPartData{Ref: 0x0107, Total: 2, Seq: 2}, parsed.GetMultiPartData())
+	require.Equal(t, []TextFormatting{{Start: 0, Length: 5, Mode: 0x10, Color: &color}}, parsed.TextFormattings())
+	require.Equal(t, []EMSObject{{ID: IEIPredefinedSound, Position: 3, Data: []byte{0x01}}}, parsed.EMSObjects())
+	_, single := parsed.GetNationalLanguage()
+	require.Equal(t, LanguageSpanish, single)
+
+	parsed.Remove(IEIPort16Bit)
+	_, _, ok = parsed.Ports()
+	require.False(t, ok)
+	require.Equal(t, MultiPartData{Ref: 0, Total: 1, Seq: 1}, NewUserDataHeader().GetMultiPartData())
+}